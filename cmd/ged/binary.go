@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// binaryMode selects what happens to file inputs that look binary.
+type binaryMode int
+
+const (
+	binarySkip    binaryMode = iota // leave the file out, with a note on stderr
+	binaryProcess                   // treat the file as text
+	binaryError                     // stop with an error
+)
+
+const (
+	// binarySniffLen is how much of a file is inspected to decide
+	// whether it is binary.
+	binarySniffLen = 8 << 10
+
+	// maxInvalidUTF8 is the fraction of bytes in invalid UTF-8 sequences
+	// above which a file is considered binary. Text in a legacy 8-bit
+	// encoding stays well below it.
+	maxInvalidUTF8 = 0.3
+)
+
+// filterBinary applies mode to the files among names whose content looks
+// binary. Stdin, and files that can't be opened, are left for processing to
+// deal with. nulIsText is set when NUL is a record separator, so NUL bytes
+// alone don't make a file binary.
+func filterBinary(names []string, mode binaryMode, nulIsText bool, stderr io.Writer) ([]string, error) {
+	if mode == binaryProcess {
+		return names, nil
+	}
+
+	var text []string
+	for _, name := range names {
+		if name != stdinName && looksBinary(name, nulIsText) {
+			if mode == binaryError {
+				return nil, fmt.Errorf("%s is a binary file (use --binary=process to edit it anyway)", name)
+			}
+			fmt.Fprintf(stderr, "ged: skipping binary file %s\n", name)
+			continue
+		}
+		text = append(text, name)
+	}
+	return text, nil
+}
+
+// looksBinary reads the start of a file, after any decompression, and
+// reports whether it is binary.
+func looksBinary(name string, nulIsText bool) bool {
+	r, err := namedInput(name, nil).open()
+	if err != nil {
+		return false
+	}
+	defer r.Close()
+
+	head := make([]byte, binarySniffLen)
+	n, _ := io.ReadFull(r, head)
+	return isBinary(head[:n], nulIsText)
+}
+
+// isBinary reports whether data contains a NUL byte (unless nulIsText is
+// set) or too many bytes that are not valid UTF-8. A multi-byte sequence cut
+// off at the end of data is not counted as invalid.
+func isBinary(data []byte, nulIsText bool) bool {
+	if len(data) == 0 {
+		return false
+	}
+
+	invalid := 0
+	for i := 0; i < len(data); {
+		if data[i] == 0 && !nulIsText {
+			return true
+		}
+		if data[i] < utf8.RuneSelf {
+			i++
+			continue
+		}
+		if !utf8.FullRune(data[i:]) {
+			break
+		}
+		r, size := utf8.DecodeRune(data[i:])
+		if r == utf8.RuneError && size == 1 {
+			invalid++
+		}
+		i += size
+	}
+	return float64(invalid) > maxInvalidUTF8*float64(len(data))
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsBinary(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		nulIsText bool
+		want      bool
+	}{
+		{"empty", "", false, false},
+		{"ascii", "hello\nworld\n", false, false},
+		{"utf-8", "héllo wörld ✓\n", false, false},
+		{"nul", "abc\x00def", false, true},
+		{"nul separated", "abc\x00def\x00", true, false},
+		{"latin-1 text", "caf\xe9 cr\xe8me br\xfbl\xe9e\n", false, false},
+		{"mostly invalid", "\xff\xfe\xfd\xfc\x80\x81ab", false, true},
+		// A rune cut off at the end of the sniffed data is not invalid
+		{"truncated rune", "abcdefgh\xe2\x9c", false, false},
+	}
+	for _, tt := range tests {
+		if got := isBinary([]byte(tt.data), tt.nulIsText); got != tt.want {
+			t.Errorf("%s: isBinary(%q) = %v, want %v", tt.name, tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestRun_SkipsBinaryFiles(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.txt": "foo\n", "b.png": "\x89PNG\r\n\x1a\n\x00\x00foo"})
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+
+	err := run([]string{"s/foo/bar/", "--", filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.png")}, strings.NewReader(""), out, errOut)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "bar\n" {
+		t.Errorf("stdout: got %q, want %q", out.String(), "bar\n")
+	}
+	if !strings.Contains(errOut.String(), "skipping binary file") || !strings.Contains(errOut.String(), "b.png") {
+		t.Errorf("stderr: got %q, want a note about b.png", errOut.String())
+	}
+}
+
+func TestRun_WriteSkipsBinaryFiles(t *testing.T) {
+	binary := "foo\x00foo"
+	dir := writeFiles(t, map[string]string{"a.bin": binary})
+	path := filepath.Join(dir, "a.bin")
+
+	err := run([]string{"--write", "s/foo/bar/", "--", path}, strings.NewReader(""), io.Discard, io.Discard)
+	if err != errNoMatch {
+		t.Fatalf("got %v, want errNoMatch", err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != binary {
+		t.Errorf("binary file was modified: %q", got)
+	}
+}
+
+func TestRun_BinaryModes(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.bin": "foo\x00foo\n"})
+	path := filepath.Join(dir, "a.bin")
+
+	err := run([]string{"--binary=error", "s/foo/bar/", "--", path}, strings.NewReader(""), io.Discard, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "binary") {
+		t.Errorf("--binary=error: got %v, want a binary file error", err)
+	}
+
+	out := &bytes.Buffer{}
+	err = run([]string{"--binary", "process", "s/foo/bar/g", "--", path}, strings.NewReader(""), out, io.Discard)
+	if err != nil {
+		t.Fatalf("--binary=process: unexpected error: %v", err)
+	}
+	if out.String() != "bar\x00bar\n" {
+		t.Errorf("--binary=process: got %q, want %q", out.String(), "bar\x00bar\n")
+	}
+
+	// NUL is a separator in -z mode, not a sign of binary content
+	out.Reset()
+	err = run([]string{"-z", "s/foo/bar/", "--", path}, strings.NewReader(""), out, io.Discard)
+	if err != nil {
+		t.Fatalf("-z: unexpected error: %v", err)
+	}
+	if out.String() != "bar\x00bar\n" {
+		t.Errorf("-z: got %q, want %q", out.String(), "bar\x00bar\n")
+	}
+}
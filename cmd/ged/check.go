@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// check runs the program over each input without printing any result.
+// Inputs that the program would change are listed on stderr, and if there
+// are any, check returns exitStatus(1) so ged can be used as a CI gate.
+func (p *program) check(inputs []input, stderr io.Writer) error {
+	changed := false
+	err := forEachOrdered(len(inputs), p.jobs, func(i int) (string, error) {
+		original, result, _, err := p.transform(inputs[i])
+		if err != nil || bytes.Equal(original, result) {
+			return "", err
+		}
+		return displayName(inputs[i].name), nil
+	}, func(name string) error {
+		if name != "" {
+			fmt.Fprintln(stderr, name)
+			changed = true
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if changed {
+		return exitStatus(1)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun_CheckReportsChangedFiles(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"clean.txt": "ok\n", "dirty.txt": "trailing \n"})
+	clean := filepath.Join(dir, "clean.txt")
+	dirty := filepath.Join(dir, "dirty.txt")
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+
+	err := run([]string{"--check", `s/\s+$//`, "--", clean, dirty}, strings.NewReader(""), out, errOut)
+	if err != exitStatus(1) {
+		t.Fatalf("got error %v, want exit status 1", err)
+	}
+
+	if out.String() != "" {
+		t.Errorf("expected no stdout, got %q", out.String())
+	}
+	if errOut.String() != dirty+"\n" {
+		t.Errorf("stderr: got %q, want %q", errOut.String(), dirty+"\n")
+	}
+}
+
+func TestRun_CheckPasses(t *testing.T) {
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+
+	err := run([]string{"--check", `s/\s+$//`}, strings.NewReader("ok\nfine\n"), out, errOut)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "" || errOut.String() != "" {
+		t.Errorf("expected no output, got stdout %q, stderr %q", out.String(), errOut.String())
+	}
+}
+
+func TestRun_CheckWithDiff(t *testing.T) {
+	err := run([]string{"--check", "--diff", "s/a/b/"}, strings.NewReader(""), io.Discard, io.Discard)
+	if err == nil {
+		t.Error("expected error for --check with --diff")
+	}
+}
+
+func TestRun_CheckMixedLineEndings(t *testing.T) {
+	// Each line keeps its own terminator, so a file mixing LF and CRLF
+	// that no rule matches is unchanged.
+	dir := writeFiles(t, map[string]string{"mixed.txt": "a\r\nb\nc\n"})
+	path := filepath.Join(dir, "mixed.txt")
+	errOut := &bytes.Buffer{}
+
+	if err := run([]string{"--check", "s/zzz/y/", "--", path}, strings.NewReader(""), io.Discard, errOut); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if errOut.String() != "" {
+		t.Errorf("expected no changed files, got %q", errOut.String())
+	}
+}
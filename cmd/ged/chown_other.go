@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// chownLike is a no-op on platforms without Unix file ownership.
+func chownLike(f *os.File, info os.FileInfo) {}
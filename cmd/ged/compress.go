@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// compression identifies how an input file is compressed.
+type compression int
+
+const (
+	uncompressed compression = iota
+	gzipped
+	bzipped
+)
+
+// Magic bytes at the start of compressed files. The bzip2 signature is
+// "BZh", a block size digit, then the block header magic (the digits of
+// pi in BCD); checking all of it keeps text that happens to start with
+// "BZh" from being mistaken for a compressed file.
+var (
+	gzipMagic   = []byte{0x1f, 0x8b, 0x08}
+	bzip2Header = []byte("BZh")
+	bzip2Block  = []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59}
+)
+
+// detectCompression identifies a file's compression from its name or,
+// failing that, from head, the first bytes of its content.
+func detectCompression(name string, head []byte) compression {
+	switch {
+	case strings.HasSuffix(name, ".gz"), bytes.HasPrefix(head, gzipMagic):
+		return gzipped
+	case strings.HasSuffix(name, ".bz2"), isBzip2(head):
+		return bzipped
+	}
+	return uncompressed
+}
+
+func isBzip2(head []byte) bool {
+	return len(head) >= 10 &&
+		bytes.HasPrefix(head, bzip2Header) &&
+		head[3] >= '1' && head[3] <= '9' &&
+		bytes.Equal(head[4:10], bzip2Block)
+}
+
+// decompress wraps a file's reader so that it reads the decompressed
+// content if the file is compressed. Closing the result closes r.
+func decompress(name string, r io.ReadCloser) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(10) // a short file just has a short head
+
+	var dr io.Reader
+	switch detectCompression(name, head) {
+	case gzipped:
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("error reading %s: %w", displayName(name), err)
+		}
+		dr = zr
+	case bzipped:
+		dr = bzip2.NewReader(br)
+	default:
+		dr = br
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{dr, r}, nil
+}
+
+// recompress compresses data the same way as original, a gzip file,
+// keeping original's header (name, comment and modification time).
+func recompress(original, data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(original))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Header = zr.Header
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// bzip2Data is "foo\nbar\n" compressed with bzip2, which the standard
+// library can only decompress.
+const bzip2Data = "\x42\x5a\x68\x39\x31\x41\x59\x26\x53\x59\xab\xf8\x61\x8b\x00\x00" +
+	"\x02\x41\x80\x00\x10\x31\x00\x90\x00\x20\x00\x30\xc0\x08\x61\xa5" +
+	"\x2c\xe8\x18\x5d\xc9\x14\xe1\x42\x42\xaf\xe1\x86\x2c"
+
+func gzipString(t *testing.T, s string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Name = "data.txt"
+	if _, err := zw.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func gunzipString(t *testing.T, s string) string {
+	t.Helper()
+	zr, err := gzip.NewReader(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestDetectCompression(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want compression
+	}{
+		{"a.txt", "plain", uncompressed},
+		{"a.gz", "", gzipped},
+		{"a.log", "\x1f\x8b\x08\x00", gzipped},
+		{"a.bz2", "", bzipped},
+		{"a.log", bzip2Data[:10], bzipped},
+		// Text that merely starts like a bzip2 header
+		{"a.txt", "BZh9 is a header", uncompressed},
+	}
+	for _, tt := range tests {
+		if got := detectCompression(tt.name, []byte(tt.head)); got != tt.want {
+			t.Errorf("detectCompression(%q, %q) = %v, want %v", tt.name, tt.head, got, tt.want)
+		}
+	}
+}
+
+func TestRun_CompressedInputs(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.log.gz":  gzipString(t, "foo 1\nbar\n"),
+		"b.log":     gzipString(t, "foo 2\n"), // detected by magic bytes
+		"c.log.bz2": bzip2Data,
+	})
+	out := &bytes.Buffer{}
+
+	err := run([]string{"p/foo/", "--", filepath.Join(dir, "a.log.gz"), filepath.Join(dir, "b.log"), filepath.Join(dir, "c.log.bz2")}, strings.NewReader(""), out, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "foo 1\nfoo 2\nfoo\n" {
+		t.Errorf("got %q, want %q", out.String(), "foo 1\nfoo 2\nfoo\n")
+	}
+}
+
+func TestRun_CorruptGzip(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.gz": "not gzip\n"})
+	err := run([]string{"p/x/", "--", filepath.Join(dir, "a.gz")}, strings.NewReader(""), io.Discard, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "a.gz") {
+		t.Errorf("got %v, want an error naming a.gz", err)
+	}
+}
+
+func TestRun_WriteGzip(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.gz": gzipString(t, "foo\nbar\n")})
+	path := filepath.Join(dir, "a.gz")
+
+	err := run([]string{"--write=.orig", "s/foo/FOO/", "--", path}, strings.NewReader(""), io.Discard, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if got := gunzipString(t, string(data)); got != "FOO\nbar\n" {
+		t.Errorf("file: got %q, want %q", got, "FOO\nbar\n")
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zr.Name != "data.txt" {
+		t.Errorf("header name: got %q, want data.txt", zr.Name)
+	}
+	backup, _ := os.ReadFile(path + ".orig")
+	if got := gunzipString(t, string(backup)); got != "foo\nbar\n" {
+		t.Errorf("backup: got %q, want the original compressed file", got)
+	}
+}
+
+func TestRun_WriteBzip2Rejected(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.bz2": bzip2Data})
+	path := filepath.Join(dir, "a.bz2")
+
+	err := run([]string{"--write", "s/foo/FOO/", "--", path}, strings.NewReader(""), io.Discard, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "bzip2") {
+		t.Errorf("got %v, want an error about bzip2", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != bzip2Data {
+		t.Error("bzip2 file was modified")
+	}
+}
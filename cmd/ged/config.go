@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/colinta/ged/internal/parser"
+)
+
+// configPath returns the path of the user's config file,
+// $XDG_CONFIG_HOME/ged/config or, if that is unset, ~/.config/ged/config.
+// It returns "" if neither location can be determined.
+func configPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "ged", "config")
+}
+
+// loadMacros reads the macros defined in the user's config file. A missing
+// config file defines no macros.
+func loadMacros() ([]*parser.Macro, error) {
+	path := configPath()
+	if path == "" {
+		return nil, nil
+	}
+	src, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	macros, err := parser.ParseMacros(path, string(src))
+	if err != nil {
+		return nil, fmt.Errorf("error reading config: %w", err)
+	}
+	return macros, nil
+}
+
+// listMacros prints the macros defined in the user's config file, for
+// --macros.
+func listMacros(stdout, stderr io.Writer) error {
+	macros, err := loadMacros()
+	if err != nil {
+		return err
+	}
+	if len(macros) == 0 {
+		fmt.Fprintf(stderr, "ged: no macros defined in %s\n", configPath())
+		return nil
+	}
+	for _, m := range macros {
+		if _, err := fmt.Fprintln(stdout, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useConfig points the config file at a temporary one with the given content.
+func useConfig(t *testing.T, content string) string {
+	t.Helper()
+	dir := writeFiles(t, map[string]string{"ged/config": content})
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	return filepath.Join(dir, "ged", "config")
+}
+
+func TestConfigPath(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+	if got := configPath(); got != filepath.Join("/xdg", "ged", "config") {
+		t.Errorf("got %q", got)
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("HOME", "/home/me")
+	if got := configPath(); got != filepath.Join("/home/me", ".config", "ged", "config") {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestRun_Macros(t *testing.T) {
+	useConfig(t, "trimws = s/\\s+$//\nrename(from, to) = s/%{from}/%{to}/g\n")
+	out := &bytes.Buffer{}
+
+	err := run([]string{"trimws; rename/cat/dog/"}, strings.NewReader("cat  \ncatcat\n"), out, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "dog\ndogdog\n" {
+		t.Errorf("got %q, want %q", out.String(), "dog\ndogdog\n")
+	}
+}
+
+func TestRun_MissingConfig(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	out := &bytes.Buffer{}
+
+	if err := run([]string{"s/a/b/"}, strings.NewReader("a\n"), out, io.Discard); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "b\n" {
+		t.Errorf("got %q, want %q", out.String(), "b\n")
+	}
+}
+
+func TestRun_ConfigError(t *testing.T) {
+	path := useConfig(t, "sort = reverse\n")
+
+	err := run([]string{"s/a/b/"}, strings.NewReader(""), io.Discard, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), path+":1:1:") {
+		t.Errorf("got %v, want an error at %s:1:1", err, path)
+	}
+}
+
+func TestRun_ListMacros(t *testing.T) {
+	useConfig(t, "# house style\ntrimws = s/\\s+$//\ntidy = trimws\n  d/^$/\n")
+	out := &bytes.Buffer{}
+
+	if err := run([]string{"--macros"}, strings.NewReader(""), out, io.Discard); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "trimws = s/\\s+$//\ntidy = trimws\n  d/^$/\n"
+	if out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/colinta/ged/internal/diff"
+)
+
+// transform runs the program over a single input and returns both the
+// original content and the result, and whether any rule matched.
+func (p *program) transform(in input) (original, result []byte, matched bool, err error) {
+	r, err := in.open()
+	if err != nil {
+		return nil, nil, false, err
+	}
+	original, err = io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("error reading %s: %w", displayName(in.name), err)
+	}
+
+	var out bytes.Buffer
+	matched, _, err = p.process([]input{bytesInput(in.name, original)}, &out)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return original, out.Bytes(), matched, nil
+}
+
+// writeDiff prints a unified diff of an input against the program's result.
+// File names get the a/ and b/ prefixes that `git apply` and `patch -p1` expect.
+// Nothing is printed for an unchanged input. Reports whether any rule matched.
+func (p *program) writeDiff(in input, stdout io.Writer) (bool, error) {
+	original, result, matched, err := p.transform(in)
+	if err != nil {
+		return false, err
+	}
+
+	name := filepath.ToSlash(displayName(in.name))
+	_, err = io.WriteString(stdout, diff.Unified(
+		"a/"+name, "b/"+name,
+		diff.SplitLines(string(original)),
+		diff.SplitLines(string(result)),
+	))
+	return matched, err
+}
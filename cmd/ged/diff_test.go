@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun_Diff(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.txt": "foo\nbar\nbaz\n"})
+	path := filepath.Join(dir, "a.txt")
+	out := &bytes.Buffer{}
+
+	err := run([]string{"--diff", "s/bar/BAR/", "--", path}, strings.NewReader(""), out, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	name := filepath.ToSlash(path)
+	want := "--- a/" + name + "\n+++ b/" + name + "\n" +
+		"@@ -1,3 +1,3 @@\n foo\n-bar\n+BAR\n baz\n"
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestRun_DiffUnchanged(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	err := run([]string{"--diff", "s/nomatch/x/"}, strings.NewReader("foo\n"), out, io.Discard)
+	if err != errNoMatch {
+		t.Fatalf("got error %v, want %v", err, errNoMatch)
+	}
+	if out.String() != "" {
+		t.Errorf("expected no output, got %q", out.String())
+	}
+}
+
+func TestRun_DiffStdin(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	err := run([]string{"--diff", "d/b/"}, strings.NewReader("a\nb\n"), out, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "--- a/stdin\n+++ b/stdin\n@@ -1,2 +1 @@\n a\n-b\n"
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestRun_DiffWithWrite(t *testing.T) {
+	err := run([]string{"--diff", "--write", "s/a/b/"}, strings.NewReader(""), io.Discard, io.Discard)
+	if err == nil {
+		t.Error("expected error for --diff with --write")
+	}
+}
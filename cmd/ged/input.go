@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/colinta/ged/internal/walk"
+)
+
+// stdinName is the input name that refers to standard input.
+const stdinName = "-"
+
+// expandInputs expands glob patterns in the input list.
+// Patterns without glob metacharacters are kept as-is, so a missing file
+// is reported when it is opened, and so is a path that exists even though
+// it contains them, such as "notes[1].txt". A pattern that matches nothing
+// is an error.
+// An empty list means stdin.
+//
+// If walkOpts is set, directories are replaced by the files below them;
+// otherwise a directory is an error.
+func expandInputs(patterns []string, walkOpts *walk.Options) ([]string, error) {
+	if len(patterns) == 0 {
+		return []string{stdinName}, nil
+	}
+
+	var paths []string
+	for _, pattern := range patterns {
+		if pattern == stdinName || !hasGlobMeta(pattern) {
+			paths = append(paths, pattern)
+			continue
+		}
+		if _, err := os.Lstat(pattern); err == nil {
+			paths = append(paths, pattern)
+			continue
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %q", pattern)
+		}
+		paths = append(paths, matches...)
+	}
+	return expandDirs(paths, walkOpts)
+}
+
+// expandDirs replaces each directory in paths with the files below it,
+// or reports an error for it if walkOpts is nil.
+func expandDirs(paths []string, walkOpts *walk.Options) ([]string, error) {
+	var expanded []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if path == stdinName || err != nil || !info.IsDir() {
+			expanded = append(expanded, path)
+			continue
+		}
+		if walkOpts == nil {
+			return nil, fmt.Errorf("%s is a directory (use -r to process the files in it)", path)
+		}
+
+		files, err := walk.Files(path, *walkOpts)
+		if err != nil {
+			return nil, fmt.Errorf("error walking %s: %w", path, err)
+		}
+		expanded = append(expanded, files...)
+	}
+	return expanded, nil
+}
+
+// hasGlobMeta reports whether a path contains any filepath.Match wildcards.
+// A backslash only escapes within a pattern, so on its own it is part of
+// the name.
+func hasGlobMeta(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// isRegularFile reports whether name is a regular file, rather than stdin,
+// a pipe or a device.
+func isRegularFile(name string) bool {
+	if name == stdinName {
+		return false
+	}
+	info, err := os.Stat(name)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// input is a named source of text. open is called when processing reaches it,
+// so a long list of files does not hold every file open at once.
+type input struct {
+	name string
+	open func() (io.ReadCloser, error)
+}
+
+// namedInput returns the input for a path. The stdin name reads from stdin,
+// wrapped so that closing it is a no-op. Compressed files are decompressed
+// as they are read.
+func namedInput(name string, stdin io.Reader) input {
+	if name == stdinName {
+		return input{name: name, open: func() (io.ReadCloser, error) {
+			return io.NopCloser(stdin), nil
+		}}
+	}
+	return input{name: name, open: func() (io.ReadCloser, error) {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		return decompress(name, f)
+	}}
+}
+
+// bytesInput returns an input that reads from data already in memory.
+func bytesInput(name string, data []byte) input {
+	return input{name: name, open: func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}}
+}
+
+// displayName returns the name used for an input in messages.
+func displayName(name string) string {
+	if name == stdinName {
+		return "stdin"
+	}
+	return name
+}
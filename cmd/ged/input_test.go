@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/colinta/ged/internal/walk"
+)
+
+// writeFiles creates files with the given contents in a temp directory
+// and returns the directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestExpandInputs_EmptyMeansStdin(t *testing.T) {
+	paths, err := expandInputs(nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(paths, []string{stdinName}) {
+		t.Errorf("got %v, want [%s]", paths, stdinName)
+	}
+}
+
+func TestExpandInputs_Glob(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"b.txt": "", "a.txt": "", "c.log": ""})
+
+	paths, err := expandInputs([]string{filepath.Join(dir, "*.txt")}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("got %v, want %v", paths, want)
+	}
+}
+
+func TestExpandInputs_GlobNoMatch(t *testing.T) {
+	dir := t.TempDir()
+	_, err := expandInputs([]string{filepath.Join(dir, "*.txt")}, nil)
+	if err == nil {
+		t.Error("expected error for glob with no matches")
+	}
+}
+
+func TestExpandInputs_PlainPathKept(t *testing.T) {
+	paths, err := expandInputs([]string{"missing.txt"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(paths, []string{"missing.txt"}) {
+		t.Errorf("got %v, want [missing.txt]", paths)
+	}
+}
+
+func TestExpandInputs_LiteralMetacharacters(t *testing.T) {
+	files := map[string]string{"notes[1].txt": "", "notes1.txt": ""}
+	if runtime.GOOS != "windows" {
+		files[`back\slash.txt`] = ""
+	}
+	dir := writeFiles(t, files)
+
+	// Existing paths are taken as they are, not as patterns
+	for name := range files {
+		if name == "notes1.txt" {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		paths, err := expandInputs([]string{path}, nil)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if !reflect.DeepEqual(paths, []string{path}) {
+			t.Errorf("%s: got %v, want [%s]", name, paths, path)
+		}
+	}
+}
+
+func TestExpandInputs_DirectoryNeedsRecursive(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.txt": ""})
+	if _, err := expandInputs([]string{dir}, nil); err == nil {
+		t.Error("expected error for a directory without -r")
+	}
+}
+
+func TestExpandInputs_Recursive(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.go":           "",
+		"b.txt":          "",
+		"sub/c.go":       "",
+		"vendor/d.go":    "",
+		"gen/e.go":       "",
+		"sub/.gitignore": "",
+		".gitignore":     "gen/\n",
+	})
+	paths, err := expandInputs([]string{dir}, &walk.Options{Include: []string{"*.go"}, Exclude: []string{"vendor/**"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{filepath.Join(dir, "a.go"), filepath.Join(dir, "sub", "c.go")}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("got %v, want %v", paths, want)
+	}
+}
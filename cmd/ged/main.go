@@ -1,112 +1,481 @@
 package main
 
 import (
-	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"strings"
 
 	"github.com/colinta/ged/internal/engine"
+	"github.com/colinta/ged/internal/lineio"
 	"github.com/colinta/ged/internal/parser"
 	"github.com/colinta/ged/internal/rule"
+	"github.com/colinta/ged/internal/walk"
 )
 
+// Exit codes follow grep: 0 if any rule matched, 1 if nothing did, 2 on error.
+// --check uses 1 to mean that some input would change. An interrupted run
+// exits with 130, as a shell reports for SIGINT.
 func main() {
-	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	// Ctrl-C stops processing at the next line, so that an error can say
+	// where. A second Ctrl-C kills ged at once, even inside a slow match.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	if err := runContext(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		var status exitStatus
+		if errors.As(err, &status) {
+			os.Exit(int(status))
+		}
+		fmt.Fprintln(os.Stderr, errorText(err))
+		if errors.Is(err, context.Canceled) {
+			os.Exit(130)
+		}
+		os.Exit(2)
+	}
+}
+
+// errorText formats err for stderr. A parse error is followed by the line
+// it is on, with a caret under the problem.
+func errorText(err error) string {
+	var perr *parser.Error
+	if errors.As(err, &perr) {
+		if caret := perr.Caret(); caret != "" {
+			return err.Error() + "\n" + caret
+		}
 	}
+	return err.Error()
+}
+
+// exitStatus is returned by run to exit with a specific status code
+// without printing an error message.
+type exitStatus int
+
+// errNoMatch is returned by run when no rule matched anything.
+const errNoMatch = exitStatus(1)
+
+func (s exitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", int(s))
 }
 
 // run executes ged with the given arguments and I/O streams.
 // This is separated from main() for testability.
 func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: ged <rule> [rule...]")
+	return runContext(context.Background(), args, stdin, stdout, stderr)
+}
+
+// runContext is run, stopping early with an error if ctx is cancelled.
+func runContext(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	opts, err := parseOptions(args)
+	if err != nil {
+		return err
 	}
+	if opts.listMacros {
+		return listMacros(stdout, stderr)
+	}
+	parsed, err := loadRules(opts)
+	if err != nil {
+		return err
+	}
+	rules, err := engine.Compile(parsed)
+	if err != nil {
+		return err
+	}
+	if opts.explain {
+		return rules.Explain(stdout)
+	}
+	prog := &program{rules: rules, ctx: ctx}
+	prog.rs, prog.eol, prog.paragraph = opts.rs, opts.eol, opts.paragraph
+	prog.jobs = opts.jobs
 
-	// Parse all rules, handling { } blocks for conditionals.
-	allParsed, err := parser.ParseArgs(args)
+	var walkOpts *walk.Options
+	if opts.recursive {
+		walkOpts = &opts.walk
+	}
+	names, err := expandInputs(opts.inputs, walkOpts)
 	if err != nil {
-		return fmt.Errorf("error parsing rules: %w", err)
-	}
-
-	// Build a list of DocumentRules.
-	// Consecutive LineRules are wrapped in an ApplyAllRule.
-	var docRules []rule.DocumentRule
-	var pendingLineRules []rule.LineRule
-
-	for _, parsed := range allParsed {
-		switch r := parsed.(type) {
-		case rule.LineRule:
-			pendingLineRules = append(pendingLineRules, r)
-		case rule.DocumentRule:
-			// Flush any pending line rules into an ApplyAllRule first
-			if len(pendingLineRules) > 0 {
-				docRules = append(docRules, rule.NewApplyAllRule(pendingLineRules))
-				pendingLineRules = nil
+		return err
+	}
+	names, err = filterBinary(names, opts.binary, strings.Contains(opts.rs, "\x00"), stderr)
+	if err != nil {
+		return err
+	}
+	// Only a regular file is chunked: output is held back until a chunk
+	// fills, which would stop stdin or a pipe from streaming.
+	prog.chunked = len(names) == 1 && isRegularFile(names[0])
+
+	if opts.mode == modeWrite {
+		for _, name := range names {
+			if name == stdinName {
+				return fmt.Errorf("--write requires file inputs")
 			}
-			docRules = append(docRules, r)
-		default:
-			return fmt.Errorf("unknown rule type from parser: %T", parsed)
 		}
+		anyMatched := false
+		err := forEachOrdered(len(names), prog.jobs, func(i int) (bool, error) {
+			return prog.writeInPlace(names[i], opts.backupSuffix)
+		}, func(matched bool) error {
+			anyMatched = anyMatched || matched
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return matchStatus(anyMatched)
 	}
 
-	// If there are no document rules, stream stdin line-by-line.
-	// This avoids buffering and works with infinite streams (e.g. tail -f).
-	if len(docRules) == 0 {
-		pipeline := engine.NewPipeline(pendingLineRules...)
-		scanner := bufio.NewScanner(stdin)
-		ctx := &rule.LineContext{}
+	var inputs []input
+	for _, name := range names {
+		inputs = append(inputs, namedInput(name, stdin))
+	}
 
-		// Call Setup on any rules that need it
-		for _, lr := range pendingLineRules {
-			if s, ok := lr.(rule.SetupRule); ok {
-				s.Setup(ctx)
-			}
+	switch opts.mode {
+	case modeDiff:
+		anyMatched := false
+		err := forEachOrdered(len(inputs), prog.jobs, func(i int) (fileResult, error) {
+			var buf bytes.Buffer
+			matched, err := prog.writeDiff(inputs[i], &buf)
+			return fileResult{output: buf.Bytes(), matched: matched}, err
+		}, func(res fileResult) error {
+			anyMatched = anyMatched || res.matched
+			_, err := stdout.Write(res.output)
+			return err
+		})
+		if err != nil {
+			return err
 		}
+		return matchStatus(anyMatched)
+	case modeCheck:
+		return prog.check(inputs, stderr)
+	}
 
-		for scanner.Scan() {
-			ctx.LineNum++
-			results, err := pipeline.Process(scanner.Text(), ctx)
-			if err != nil {
-				return fmt.Errorf("error applying rules: %w", err)
-			}
-			if ctx.Printing == rule.PrintOff {
-				continue
+	// Each input is its own document with a fresh LineContext, so line
+	// numbers, control rules and between ranges start over per file.
+	// With --concat, all inputs are processed as a single stream.
+	if opts.concat {
+		matched, _, err := prog.process(inputs, stdout)
+		if err != nil {
+			return err
+		}
+		return matchStatus(matched)
+	}
+	// When printing several inputs one after another, an input without a
+	// final newline would run into the next one, so it is ended with its
+	// own terminator.
+	// Terminators are at most twice the length of the default one: CRLF
+	// rather than LF, or a blank line of them between paragraphs.
+	out := &trailingWriter{w: stdout, keep: 2 * len(prog.outputSep())}
+	anyMatched := false
+	// A single input is written as it is processed, even with -j, so that
+	// stdin streams; a large file is still split into chunks.
+	if prog.jobs <= 1 || len(inputs) == 1 {
+		for _, in := range inputs {
+			if err := out.endLine(); err != nil {
+				return err
 			}
-			for _, result := range results {
-				fmt.Fprintln(stdout, result)
+			matched, sep, err := prog.process([]input{in}, out)
+			if err != nil {
+				return err
 			}
+			out.sep = sep
+			anyMatched = anyMatched || matched
 		}
-		return scanner.Err()
+		return matchStatus(anyMatched)
 	}
 
-	// Document rules exist — flush any trailing line rules and buffer all input.
-	if len(pendingLineRules) > 0 {
-		docRules = append(docRules, rule.NewApplyAllRule(pendingLineRules))
+	// With -j, inputs are processed in parallel, each into its own buffer,
+	// and the buffers are printed in input order.
+	err = forEachOrdered(len(inputs), prog.jobs, func(i int) (fileResult, error) {
+		var buf bytes.Buffer
+		matched, sep, err := prog.process([]input{inputs[i]}, &buf)
+		return fileResult{buf.Bytes(), matched, sep}, err
+	}, func(res fileResult) error {
+		if err := out.endLine(); err != nil {
+			return err
+		}
+		anyMatched = anyMatched || res.matched
+		_, err := out.Write(res.output)
+		out.sep = res.sep
+		return err
+	})
+	if err != nil {
+		return err
 	}
+	return matchStatus(anyMatched)
+}
 
-	scanner := bufio.NewScanner(stdin)
-	var lines []string
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+// trailingWriter records how the output so far ends, so that an input's
+// output can be completed with its terminator, sep, before the next one
+// starts. An input without a final newline needs all of sep; the last
+// paragraph of an input is followed by a single line terminator, so it
+// needs the rest of the blank line.
+type trailingWriter struct {
+	w    io.Writer
+	sep  string // the last input's terminator, set after its output
+	keep int    // how much of the output to keep in tail
+	tail []byte // the end of the output since the last endLine
+}
+
+func (t *trailingWriter) Write(p []byte) (int, error) {
+	if len(p) >= t.keep {
+		t.tail = append(t.tail[:0], p[len(p)-t.keep:]...)
+	} else {
+		t.tail = append(t.tail, p...)
+		if len(t.tail) > t.keep {
+			t.tail = append(t.tail[:0], t.tail[len(t.tail)-t.keep:]...)
+		}
 	}
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("error reading input: %w", err)
+	return t.w.Write(p)
+}
+
+// endLine writes whatever part of sep the output since the last call does
+// not already end with. Nothing is written after no output.
+func (t *trailingWriter) endLine() error {
+	if len(t.tail) == 0 {
+		return nil
 	}
+	missing := t.sep
+	for n := min(len(t.sep), len(t.tail)); n > 0; n-- {
+		if bytes.HasSuffix(t.tail, []byte(t.sep[:n])) {
+			missing = t.sep[n:]
+			break
+		}
+	}
+	_, err := io.WriteString(t.w, missing)
+	t.tail = t.tail[:0]
+	return err
+}
 
-	for _, dr := range docRules {
-		var err error
-		lines, err = dr.ApplyDocument(lines)
+// matchStatus returns the error run reports for a successful run:
+// nil if any rule matched, errNoMatch otherwise.
+func matchStatus(matched bool) error {
+	if matched {
+		return nil
+	}
+	return errNoMatch
+}
+
+// program is a compiled rule list and the options for reading and writing
+// the inputs it is applied to.
+type program struct {
+	rules     *engine.Program
+	ctx       context.Context // cancels processing
+	rs        string          // input record separator; "" means LF or CRLF lines
+	eol       string          // line terminator to force on output; "" keeps the input's
+	paragraph bool            // records are paragraphs rather than lines
+	jobs      int             // number of inputs, or chunks of a single input, to process at once
+	chunked   bool            // the run has a single input, which may be split into chunks
+}
+
+// outputSep returns the terminator written after each output record when
+// it does not come from the input itself.
+func (p *program) outputSep() string {
+	switch {
+	case p.paragraph:
+		if p.eol != "" {
+			return p.eol + p.eol
+		}
+		return lineio.ParagraphSep
+	case p.eol != "":
+		return p.eol
+	case p.rs != "":
+		return p.rs
+	default:
+		return lineio.LF
+	}
+}
+
+// loadRules parses the rules from the script files, or else from the
+// rule arguments. { } blocks for conditionals are handled by the parser,
+// and macros from the user's config file can be used in either.
+func loadRules(opts *options) ([]any, error) {
+	if len(opts.scripts) == 0 && len(opts.rules) < 1 {
+		return nil, errors.New(usage)
+	}
+	macros, err := loadMacros()
+	if err != nil {
+		return nil, err
+	}
+	parseOpts := []parser.Option{parser.WithMacros(macros), parser.WithDialect(opts.dialect)}
+	if opts.regexTimeout > 0 {
+		parseOpts = append(parseOpts, parser.WithMatchTimeout(opts.regexTimeout))
+	}
+
+	if len(opts.scripts) == 0 {
+		parsed, err := parser.ParseArgs(opts.rules, parseOpts...)
 		if err != nil {
-			return fmt.Errorf("error applying rules: %w", err)
+			// The parser counts rule arguments; the user counts all of them.
+			var perr *parser.Error
+			if errors.As(err, &perr) && perr.Arg > 0 && perr.Arg <= len(opts.ruleArg) {
+				perr.Arg = opts.ruleArg[perr.Arg-1] + 1
+			}
+			return nil, fmt.Errorf("error parsing rules: %w", err)
 		}
+		// Blank and comment-only arguments parse to nothing; don't run as cat.
+		if len(parsed) == 0 {
+			return nil, errors.New(usage)
+		}
+		return parsed, nil
 	}
 
-	for _, line := range lines {
-		fmt.Fprintln(stdout, line)
+	var allParsed []any
+	for _, path := range opts.scripts {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := parser.ParseScript(path, string(src), parseOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing script: %w", err)
+		}
+		allParsed = append(allParsed, parsed...)
 	}
+	if len(allParsed) == 0 {
+		return nil, fmt.Errorf("no rules in %s", strings.Join(opts.scripts, ", "))
+	}
+	return allParsed, nil
+}
 
+// process runs the program over the inputs as a single document.
+// A fresh LineContext is used for every call.
+// Output uses the input's line terminators (or p.eol, if set), and a missing
+// final newline stays missing. Reports whether any rule matched, and sep,
+// the input's terminator, for ending the output if another input follows.
+func (p *program) process(inputs []input, stdout io.Writer) (matched bool, sep string, err error) {
+	ctx := &rule.LineContext{RecordSep: p.recordSep(), Context: p.ctx}
+
+	// A single input through stateless rules only can be split into
+	// chunks that are processed in parallel.
+	if pipeline := p.rules.Pipeline(); pipeline != nil && p.jobs > 1 && p.chunked && len(inputs) == 1 && pipeline.Stateless() {
+		sep, err := p.processChunked(pipeline, inputs[0], ctx, stdout)
+		return ctx.Matched, sep, err
+	}
+
+	sep = p.outputSep()
+	records := func(fn func(line, eol, end string) error) error {
+		return p.eachLine(inputs, func(line, eol, end string) error {
+			sep = eol
+			return fn(line, eol, end)
+		})
+	}
+	err = p.rules.Process(records, ctx, stdout, p.outputSep())
+	return ctx.Matched, sep, err
+}
+
+// processChunked streams one input through a stateless pipeline, with
+// chunks of lines processed on up to p.jobs goroutines. The output is the
+// same as for sequential processing. It returns the input's terminator.
+func (p *program) processChunked(pipeline *engine.Pipeline, in input, ctx *rule.LineContext, stdout io.Writer) (string, error) {
+	r, err := in.open()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	lines := p.newReader(r)
+
+	// Read and write errors are returned as they are; anything else
+	// comes from the rules.
+	var ioErr error
+	// Lines are read ahead of their output, so each line's terminator is
+	// queued until the line is emitted.
+	var ends []string
+	next := func() (string, error) {
+		line, err := lines.ReadLine()
+		if err != nil && err != io.EOF {
+			ioErr = fmt.Errorf("error reading %s: %w", displayName(in.name), err)
+			return "", ioErr
+		}
+		if err == nil {
+			ends = append(ends, lines.End())
+		}
+		return line, err
+	}
+	emit := func(results []string, _ bool) error {
+		end := ends[0]
+		ends = ends[1:]
+		if err := lineio.WriteLines(stdout, results, lines.Terminator(), end); err != nil {
+			ioErr = err
+			return err
+		}
+		return nil
+	}
+
+	err = pipeline.ProcessChunked(p.jobs, ctx, next, emit)
+	if err != nil && err != ioErr {
+		return "", fmt.Errorf("error applying rules: %w", err)
+	}
+	return lines.Terminator(), err
+}
+
+// recordSep returns the separator rules split their output on.
+func (p *program) recordSep() string {
+	if p.paragraph {
+		return lineio.ParagraphSep
+	}
+	return p.rs
+}
+
+// newReader returns a reader splitting r into the program's records.
+func (p *program) newReader(r io.Reader) *lineio.Reader {
+	var lines *lineio.Reader
+	switch {
+	case p.paragraph:
+		lines = lineio.NewParagraphReader(r)
+	case p.rs != "":
+		lines = lineio.NewRecordReader(r, p.rs)
+	default:
+		lines = lineio.NewReader(r)
+	}
+	if p.eol != "" {
+		lines.SetTerminator(p.eol)
+	}
+	return lines
+}
+
+// eachLine calls fn with every line of the inputs, in order. Lines may be of
+// any length. eol is the terminator to write between the line's outputs and
+// end the one to write after the last: the input's own, unless p.eol forces
+// one. end is "" only for a final line without a trailing newline. When
+// several inputs form one stream, a missing newline at the end of any but
+// the last input is supplied.
+func (p *program) eachLine(inputs []input, fn func(line, eol, end string) error) error {
+	for i, in := range inputs {
+		r, err := in.open()
+		if err != nil {
+			return err
+		}
+
+		lines := p.newReader(r)
+		for {
+			var line string
+			line, err = lines.ReadLine()
+			if err != nil {
+				if err == io.EOF {
+					err = nil
+				} else {
+					err = fmt.Errorf("error reading %s: %w", displayName(in.name), err)
+				}
+				break
+			}
+
+			eol, end := lines.Terminator(), lines.End()
+			if end == "" && i < len(inputs)-1 {
+				end = eol
+			}
+			if err = fn(line, eol, end); err != nil {
+				break
+			}
+		}
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
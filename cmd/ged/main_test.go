@@ -2,9 +2,13 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io"
+	"path/filepath"
 	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestRun_BasicSubstitution(t *testing.T) {
@@ -16,7 +20,7 @@ func TestRun_BasicSubstitution(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "hello earth\n"
+	want := "hello earth"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -31,7 +35,7 @@ func TestRun_GlobalSubstitution(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "hello earth earth\n"
+	want := "hello earth earth"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -46,7 +50,7 @@ func TestRun_MultipleLines(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "row1\nrow2\nrow3\n"
+	want := "row1\nrow2\nrow3"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -61,7 +65,7 @@ func TestRun_RegexPattern(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "foo NUM bar NUM\n"
+	want := "foo NUM bar NUM"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -77,6 +81,19 @@ func TestRun_NoArgs(t *testing.T) {
 	}
 }
 
+func TestRun_EmptyRules(t *testing.T) {
+	for _, rules := range [][]string{{""}, {";"}, {"# x"}, {"", " "}} {
+		out := &bytes.Buffer{}
+		err := run(rules, strings.NewReader("hello\n"), out, io.Discard)
+		if err == nil || err.Error() != usage {
+			t.Errorf("%q: got %v, want the usage error", rules, err)
+		}
+		if out.Len() != 0 {
+			t.Errorf("%q: wrote %q, want no output", rules, out.String())
+		}
+	}
+}
+
 func TestRun_InvalidRule(t *testing.T) {
 	in := strings.NewReader("hello")
 	out := &bytes.Buffer{}
@@ -97,13 +114,91 @@ func TestRun_InvalidRegex(t *testing.T) {
 	}
 }
 
+func TestErrorText_Caret(t *testing.T) {
+	err := run([]string{"s/a/b/", "p/x/; revers"}, strings.NewReader(""), io.Discard, io.Discard)
+	want := "error parsing rules: argument 2, column 7: unknown command \"revers\"; did you mean \"reverse\"?\n" +
+		"  p/x/; revers\n" +
+		"        ^"
+	if got := errorText(err); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	if got := errorText(errors.New("plain")); got != "plain" {
+		t.Errorf("got %q, want the error alone", got)
+	}
+}
+
+func TestRun_ErrorArgumentCountsFlags(t *testing.T) {
+	args := []string{"--regex-timeout", "1s", "-j", "2", "s/a/b/", "revers"}
+	err := run(args, strings.NewReader(""), io.Discard, io.Discard)
+	want := "error parsing rules: argument 6, column 1: unknown command"
+	if err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("got %v, want %q", err, want)
+	}
+}
+
+func TestRun_RegexTimeout(t *testing.T) {
+	in := strings.NewReader("ok\n" + strings.Repeat("a", 40) + "!\n")
+
+	err := run([]string{"--regex-timeout=10ms", "s/(a+)+$/x/"}, in, io.Discard, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "line 2: s/(a+)+$/x/:") {
+		t.Errorf("got %v, want a timeout at line 2 in s/(a+)+$/x/", err)
+	}
+}
+
+func TestRun_RegexDialect(t *testing.T) {
+	out := &bytes.Buffer{}
+	err := run([]string{"--regex=re2", `s/(\w+)@(\w+)/$2 at $1/`}, strings.NewReader("me@home\n"), out, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "home at me\n" {
+		t.Errorf("got %q, want %q", out.String(), "home at me\n")
+	}
+
+	// RE2 has no backreferences; the e flag brings them back for one rule
+	err = run([]string{"--regex=re2", `p/(a)\1/`}, strings.NewReader(""), io.Discard, io.Discard)
+	if err == nil {
+		t.Error("expected error for a backreference in re2")
+	}
+	err = run([]string{"--regex=re2", `p/(a)\1/e`}, strings.NewReader("aa\n"), io.Discard, io.Discard)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRun_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := runContext(ctx, []string{"s/a/b/"}, strings.NewReader("a\n"), io.Discard, io.Discard)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestRun_Explain(t *testing.T) {
+	out := &bytes.Buffer{}
+	// The input is never read: reading it would fail.
+	in := iotest.ErrReader(errors.New("input was read"))
+	if err := run([]string{"--explain", "s/a/b/", "if/x/ { sort }"}, in, out, io.Discard); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"mode: buffered (needs the whole input: if/x/)", "ApplyAllRule\n  SubstitutionRule", "ConditionalDocRule if/x/", "\n  SortRule\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("got\n%s\nwant it to contain %q", out, want)
+		}
+	}
+}
+
 func TestRun_EmptyInput(t *testing.T) {
 	in := strings.NewReader("")
 	out := &bytes.Buffer{}
 
+	// Nothing to match, so run reports grep's "no match" status
 	err := run([]string{"s/foo/bar"}, in, out, io.Discard)
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	if err != errNoMatch {
+		t.Fatalf("got error %v, want %v", err, errNoMatch)
 	}
 
 	if out.String() != "" {
@@ -120,7 +215,7 @@ func TestRun_PrintKeepsMatchingLines(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "foo\nfoo baz\n"
+	want := "foo\nfoo baz"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -135,7 +230,7 @@ func TestRun_PrintWithRegex(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "123\n456\n"
+	want := "123\n456"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -181,7 +276,7 @@ func TestRun_MultipleRules(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "hell0\nhell0 w0rld\n"
+	want := "hell0\nhell0 w0rld"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -198,7 +293,7 @@ func TestRun_ChainedSubstitutions(t *testing.T) {
 	}
 
 	// "abc" -> "bbc" -> "cbc"
-	want := "cbc\n"
+	want := "cbc"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -214,7 +309,7 @@ func TestRun_FilterDeletesBeforeSubstitute(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "KEEP this\nKEEP that\n"
+	want := "KEEP this\nKEEP that"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -229,7 +324,7 @@ func TestRun_Sort(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "a\nb\nc\n"
+	want := "a\nb\nc"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -244,7 +339,7 @@ func TestRun_Reverse(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "c\nb\na\n"
+	want := "c\nb\na"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -259,7 +354,7 @@ func TestRun_JoinWithComma(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "a,b,c\n"
+	want := "a,b,c"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -275,7 +370,7 @@ func TestRun_LineRulesThenSort(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "a\nb\nc\n"
+	want := "a\nb\nc"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -291,7 +386,7 @@ func TestRun_SortThenLineRules(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "Apple\nbAnana\ncherry\n"
+	want := "Apple\nbAnana\ncherry"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -306,7 +401,7 @@ func TestRun_JoinBare(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "abc\n"
+	want := "abc"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -322,7 +417,7 @@ func TestRun_IfCondition(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "hellx\nworld\nhellx world\n"
+	want := "hellx\nworld\nhellx world"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -338,7 +433,7 @@ func TestRun_IfConditionInverted(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "hello\nwxrld\nhello world\n"
+	want := "hello\nwxrld\nhello world"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -354,7 +449,7 @@ func TestRun_IfWithMultipleInnerRules(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "HEllo\nworld\nHEllo world\n"
+	want := "HEllo\nworld\nHEllo world"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -370,7 +465,7 @@ func TestRun_IfThenSort(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "a\nb\nc_world\n"
+	want := "a\nb\nc_world"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -388,7 +483,7 @@ func TestRun_IfWithDocumentRule(t *testing.T) {
 
 	// items sorted: a_item, b_item, d_item woven back into positions 0,1,3
 	// c_other stays at position 2
-	want := "a_item\nb_item\nc_other\nd_item\n"
+	want := "a_item\nb_item\nc_other\nd_item"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -404,7 +499,7 @@ func TestRun_NestedIf(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "AB\nac\nbd\nbc\n"
+	want := "AB\nac\nbd\nbc"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -419,7 +514,7 @@ func TestRun_On(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "start\nb\nc\n"
+	want := "start\nb\nc"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -449,7 +544,7 @@ func TestRun_After(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "b\nc\n"
+	want := "b\nc"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -479,7 +574,7 @@ func TestRun_OnWithSubstitution(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "start\nB\nc\n"
+	want := "start\nB\nc"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -509,7 +604,7 @@ func TestRun_Between(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "before\nSTART\nx\nx\nEND\nafter\n"
+	want := "before\nSTART\nx\nx\nEND\nafter"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -524,7 +619,7 @@ func TestRun_BetweenInverted(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "X\nSTART\nx\nEND\nX\n"
+	want := "X\nSTART\nx\nEND\nX"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -539,7 +634,7 @@ func TestRun_BetweenMultipleRanges(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "x\nA\nX\nB\nx\nA\nX\nB\nx\n"
+	want := "x\nA\nX\nB\nx\nA\nX\nB\nx"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -554,7 +649,7 @@ func TestRun_BetweenWithDocRule(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "before\nEND\nSTART\na\nb\nc\nafter\n"
+	want := "before\nEND\nSTART\na\nb\nc\nafter"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -571,7 +666,7 @@ func TestRun_SubstitutionIgnoreCase(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "HI World\n"
+	want := "HI World"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -586,7 +681,7 @@ func TestRun_SubstitutionIgnoreCaseGlobal(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "x x x\n"
+	want := "x x x"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -601,7 +696,7 @@ func TestRun_PrintIgnoreCase(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "Hello\nHELLO\n"
+	want := "Hello\nHELLO"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -631,7 +726,7 @@ func TestRun_IfIgnoreCase(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "HeLLo\nworld\nHELLO\n"
+	want := "HeLLo\nworld\nHELLO"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -646,7 +741,7 @@ func TestRun_OnIgnoreCase(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "START\nb\nc\n"
+	want := "START\nb\nc"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
@@ -661,8 +756,391 @@ func TestRun_BetweenIgnoreCase(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	want := "before\nStart\nx\nx\nEnd\nafter\n"
+	want := "before\nStart\nx\nx\nEnd\nafter"
 	if out.String() != want {
 		t.Errorf("got %q, want %q", out.String(), want)
 	}
 }
+
+// --- File input tests ---
+
+func TestRun_FileInputs(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.txt": "foo\nbar", "b.txt": "foo baz"})
+	out := &bytes.Buffer{}
+
+	err := run([]string{"s/foo/FOO/", "--", filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")}, strings.NewReader(""), out, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "FOO\nbar\nFOO baz"
+	if out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
+
+func TestRun_InputGlob(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.txt": "a", "b.txt": "b", "c.log": "c"})
+	out := &bytes.Buffer{}
+
+	err := run([]string{"--input", filepath.Join(dir, "*.txt"), "s/$/!/"}, strings.NewReader(""), out, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "a!\nb!"
+	if out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
+
+func TestRun_FreshContextPerFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.txt": "a1\na2\na3", "b.txt": "b1\nb2\nb3"})
+	out := &bytes.Buffer{}
+
+	// Line numbers restart for each file
+	err := run([]string{"p:1-2", "--", filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")}, strings.NewReader(""), out, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "a1\na2\nb1\nb2\n"
+	if out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
+
+func TestRun_FreshControlStatePerFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.txt": "x\nstart\ny", "b.txt": "z\nstart\nw"})
+	out := &bytes.Buffer{}
+
+	err := run([]string{"on/start/", "--", filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")}, strings.NewReader(""), out, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "start\ny\nstart\nw"
+	if out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
+
+func TestRun_ConcatInputs(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.txt": "a1\na2", "b.txt": "b1\nb2"})
+	out := &bytes.Buffer{}
+
+	err := run([]string{"--concat", "p:2-3", "--", filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")}, strings.NewReader(""), out, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "a2\nb1\n"
+	if out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
+
+func TestRun_FilesWithDocumentRule(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.txt": "b\na", "b.txt": "d\nc"})
+	out := &bytes.Buffer{}
+
+	// Each file is sorted on its own
+	err := run([]string{"sort", "--", filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")}, strings.NewReader(""), out, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "a\nb\nc\nd"
+	if out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
+
+func TestRun_MissingFile(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	err := run([]string{"s/a/b/", "--", filepath.Join(t.TempDir(), "missing.txt")}, strings.NewReader(""), out, io.Discard)
+	if err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+// --- Exit status tests ---
+
+func TestRun_NoMatchStatus(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []string
+		input string
+		want  error
+	}{
+		{"print matches", []string{"p/foo/"}, "foo\nbar", nil},
+		{"print filters everything", []string{"p/nope/"}, "foo\nbar", errNoMatch},
+		{"delete matches", []string{"d/foo/"}, "foo\nbar", nil},
+		{"delete nothing", []string{"d/nope/"}, "foo\nbar", errNoMatch},
+		{"substitution matches", []string{"s/foo/baz/"}, "foo\nbar", nil},
+		{"substitution to same text still matches", []string{"s/foo/foo/"}, "foo", nil},
+		{"substitution never matches", []string{"s/nope/x/"}, "foo\nbar", errNoMatch},
+		{"if matches", []string{"if/foo/", "{", "s/x/y/", "}"}, "foo", nil},
+		{"if never matches", []string{"if/nope/", "{", "s/x/y/", "}"}, "foo", errNoMatch},
+		{"if with doc rule never matches", []string{"if/nope/", "{", "sort", "}"}, "foo", errNoMatch},
+		{"between matches", []string{"between/a/b/", "{", "sort", "}"}, "a\nb", nil},
+		{"sort on non-empty input", []string{"sort"}, "b\na", nil},
+		{"line rules before sort never match", []string{"p/nope/", "sort"}, "b\na", errNoMatch},
+		{"line number in range", []string{"p:2"}, "a\nb", nil},
+		{"line number out of range", []string{"p:5"}, "a\nb", errNoMatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := run(tt.rules, strings.NewReader(tt.input), io.Discard, io.Discard)
+			if err != tt.want {
+				t.Errorf("got error %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun_NoMatchAcrossFiles(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.txt": "nothing", "b.txt": "foo"})
+
+	// A match in any file counts
+	err := run([]string{"p/foo/", "--", filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")}, strings.NewReader(""), io.Discard, io.Discard)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRun_LongLines(t *testing.T) {
+	// Longer than bufio.Scanner's 64 KiB default token limit
+	long := strings.Repeat("x", 100_000)
+	want := "y" + long[1:] + "\nzzz"
+
+	// Streaming (line rules only) and buffered (document rules) paths
+	for _, rules := range [][]string{{"s/^x/y/"}, {"s/^x/y/", "sort"}} {
+		out := &bytes.Buffer{}
+		err := run(rules, strings.NewReader(long+"\nzzz"), out, io.Discard)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", rules, err)
+		}
+		if out.String() != want {
+			t.Errorf("%v: long line not processed intact", rules)
+		}
+	}
+}
+
+// --- Line ending tests ---
+
+func TestRun_LineEndings(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []string
+		input string
+		want  string
+	}{
+		{"final newline kept", []string{"s/a/b/"}, "a\na\n", "b\nb\n"},
+		{"missing final newline kept", []string{"s/a/b/"}, "a\na", "b\nb"},
+		{"CRLF kept", []string{"s/a/b/"}, "a\r\na\r\n", "b\r\nb\r\n"},
+		{"CRLF without final newline", []string{"s/a/b/"}, "a\r\na", "b\r\nb"},
+		{"CRLF with document rule", []string{"sort"}, "b\r\na\r\n", "a\r\nb\r\n"},
+		{"missing final newline with document rule", []string{"sort"}, "b\na", "a\nb"},
+		{"dollar matches before CR", []string{"s/a$/b/"}, "a\r\n", "b\r\n"},
+		{"new lines use input terminator", []string{`s/-/\n/`}, "a-b\r\n", "a\r\nb\r\n"},
+		{"deleted final line", []string{"d/c/"}, "a\nb\nc", "a\nb\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			err := run(tt.rules, strings.NewReader(tt.input), out, io.Discard)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("got %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRun_ForceEOL(t *testing.T) {
+	out := &bytes.Buffer{}
+	err := run([]string{"--eol=lf", "s/a/b/"}, strings.NewReader("a\r\na"), out, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "b\nb" {
+		t.Errorf("got %q, want %q", out.String(), "b\nb")
+	}
+
+	out.Reset()
+	err = run([]string{"--eol", "crlf", "sort"}, strings.NewReader("b\na\n"), out, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "a\r\nb\r\n" {
+		t.Errorf("got %q, want %q", out.String(), "a\r\nb\r\n")
+	}
+}
+
+func TestRun_InvalidEOL(t *testing.T) {
+	err := run([]string{"--eol=cr", "s/a/b/"}, strings.NewReader(""), io.Discard, io.Discard)
+	if err == nil || err == errNoMatch {
+		t.Errorf("expected error for invalid --eol, got %v", err)
+	}
+}
+
+// --- Record separator tests ---
+
+func TestRun_RecordSeparators(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		input string
+		want  string
+	}{
+		{"null records", []string{"-z", "p/a/"}, "a 1\x00b\x00a\n2\x00", "a 1\x00a\n2\x00"},
+		{"null records keep newlines", []string{"-z", "s/\n/ /g"}, "a\nb\x00c\x00", "a b\x00c\x00"},
+		{"null records sorted", []string{"--null", "sort"}, "b\x00a\x00", "a\x00b\x00"},
+		{"null records by line number", []string{"-z", "d:2"}, "a\x00b\x00c", "a\x00c"},
+		{"custom separator", []string{`--rs=\n---\n`, "reverse"}, "a\n---\nb\n---\n", "b\n---\na\n---\n"},
+		{"custom output separator", []string{"--rs=,", "--ors=;", "s/x/y/"}, "x,x,x", "y;y;y"},
+		{"lines to null", []string{`--ors=\0`, "p/./"}, "a\nb\n", "a\x00b\x00"},
+		{"joined records", []string{"-z", "join/,/"}, "a\x00b\x00", "a,b\x00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			err := run(tt.args, strings.NewReader(tt.input), out, io.Discard)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("got %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRun_NullRecordsAcrossFiles(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a": "x\x00y", "b": "z\x00"})
+	out := &bytes.Buffer{}
+
+	// The missing separator at the end of the first file is supplied
+	err := run([]string{"-z", "s/^/-/", "--", filepath.Join(dir, "a"), filepath.Join(dir, "b")}, strings.NewReader(""), out, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "-x\x00-y\x00-z\x00" {
+		t.Errorf("got %q, want %q", out.String(), "-x\x00-y\x00-z\x00")
+	}
+}
+
+func TestRun_MissingNewlineBetweenFiles(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a": "x\r\ny", "b": "z\n"})
+	want := "x\r\ny\r\nz\n"
+
+	// The first file's missing terminator is supplied in its own style
+	for _, jobs := range []string{"1", "2"} {
+		out := &bytes.Buffer{}
+		err := run([]string{"-j", jobs, "s/q/r/", "--", filepath.Join(dir, "a"), filepath.Join(dir, "b")}, strings.NewReader(""), out, io.Discard)
+		if err != errNoMatch {
+			t.Fatalf("-j %s: got error %v, want %v", jobs, err, errNoMatch)
+		}
+		if out.String() != want {
+			t.Errorf("-j %s: got %q, want %q", jobs, out.String(), want)
+		}
+	}
+}
+
+func TestRun_ParagraphsAcrossFiles(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"p1": "a1\na2\n", "p2": "b1\nb2\n", "p3": "c1\r\n\r\nc2\r\n\r\n\r\n"})
+	want := "aX\na2\n\nbX\nb2\n\ncX\r\n\r\nc2\r\n"
+
+	// A blank line separates the last paragraph of one file from the next
+	for _, jobs := range []string{"1", "2"} {
+		out := &bytes.Buffer{}
+		args := []string{"-j", jobs, "--paragraph", "s/1/X/", "--", filepath.Join(dir, "p1"), filepath.Join(dir, "p2"), filepath.Join(dir, "p3")}
+		if err := run(args, strings.NewReader(""), out, io.Discard); err != nil {
+			t.Fatalf("-j %s: unexpected error: %v", jobs, err)
+		}
+		if out.String() != want {
+			t.Errorf("-j %s: got %q, want %q", jobs, out.String(), want)
+		}
+	}
+}
+
+func TestRun_Paragraphs(t *testing.T) {
+	input := "[b]\nx = 1\n\n\n[a]\ny = 2\n"
+	tests := []struct {
+		name  string
+		args  []string
+		input string
+		want  string
+	}{
+		{"print paragraphs", []string{"--paragraph", "p/y/"}, input, "[a]\ny = 2\n"},
+		// As with lines, deleting the final record while streaming keeps the
+		// separator already written after the one before it
+		{"delete final paragraph", []string{"--paragraph", "p/x/"}, input, "[b]\nx = 1\n\n"},
+		{"delete paragraphs", []string{"--paragraph", "d/x/"}, input, "[a]\ny = 2\n"},
+		{"sort paragraphs", []string{"--paragraph", "sort"}, input, "[a]\ny = 2\n\n[b]\nx = 1\n"},
+		{"regex sees newlines", []string{"--paragraph", `s/\n/; /g`}, input, "[b]; x = 1\n\n[a]; y = 2\n"},
+		{"leading blank lines dropped", []string{"--paragraph", "s/a/A/"}, "\n\na\n\nb", "A\n\nb"},
+		{"numbered paragraphs", []string{"--paragraph", "d:1"}, "a\n\nb\nc\n\nd\n", "b\nc\n\nd\n"},
+		{"crlf paragraphs", []string{"--paragraph", "reverse"}, "a\r\nb\r\n\r\nc\r\n", "c\r\n\r\na\r\nb\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			err := run(tt.args, strings.NewReader(tt.input), out, io.Discard)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("got %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRun_ScriptFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"fix.ged": "# upper-case the greetings\nif/^hello/ {\n  s/hello/HELLO/\n}\nd/^#/\n",
+		"a.txt":   "hello a\n# note\nbye\n",
+	})
+	out := &bytes.Buffer{}
+
+	// With -f, positional arguments are inputs
+	err := run([]string{"-f", filepath.Join(dir, "fix.ged"), filepath.Join(dir, "a.txt")}, strings.NewReader(""), out, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "HELLO a\nbye\n" {
+		t.Errorf("got %q, want %q", out.String(), "HELLO a\nbye\n")
+	}
+}
+
+func TestRun_SingleArgumentScript(t *testing.T) {
+	out := &bytes.Buffer{}
+	in := strings.NewReader("# x\nfoo b\nfoo a\nbar\n")
+
+	err := run([]string{"if/foo/ { s/a/A/g; sort }; d/^#/"}, in, out, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "foo A\nfoo b\nbar\n" {
+		t.Errorf("got %q, want %q", out.String(), "foo A\nfoo b\nbar\n")
+	}
+}
+
+func TestRun_ScriptFileError(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"bad.ged": "sort\n  frob/x/\n"})
+	path := filepath.Join(dir, "bad.ged")
+
+	err := run([]string{"-f", path}, strings.NewReader(""), io.Discard, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), path+":2:3:") {
+		t.Errorf("got %v, want an error at %s:2:3", err, path)
+	}
+}
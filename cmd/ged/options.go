@@ -0,0 +1,296 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/colinta/ged/internal/lineio"
+	"github.com/colinta/ged/internal/rule"
+	"github.com/colinta/ged/internal/walk"
+)
+
+const usage = "usage: ged [options] <rule> [rule...] [-- file...]\n       ged [options] -f script [file...]"
+
+// outputMode selects what ged does with each input's result.
+type outputMode int
+
+const (
+	modePrint outputMode = iota // print the result to stdout
+	modeWrite                   // rewrite each input file in place
+	modeDiff                    // print a unified diff of each input
+	modeCheck                   // report inputs that would change, print nothing
+)
+
+// modeFlags names the flag that selects each non-default mode, for messages.
+var modeFlags = map[outputMode]string{
+	modeWrite: "--write",
+	modeDiff:  "--diff",
+	modeCheck: "--check",
+}
+
+// options holds the parsed command line: flags, rule arguments, and inputs.
+type options struct {
+	rules   []string // rule arguments, passed to parser.ParseArgs
+	ruleArg []int    // the index in the arguments of each rule, for errors
+	scripts []string // script files given with -f; rules come from these instead
+	inputs  []string // file paths or glob patterns; empty means stdin
+	concat  bool     // treat all inputs as one stream instead of one document per file
+	rs      string   // input record separator; "" means LF or CRLF lines
+	eol     string   // line terminator to force on output; "" keeps each input's own
+
+	paragraph bool // records are blank-line separated paragraphs
+
+	recursive bool         // process the files below directory inputs
+	walk      walk.Options // which files a recursive walk lists
+	binary    binaryMode   // what to do with files that look binary
+	jobs      int          // number of files to process at once
+
+	regexTimeout time.Duration // limit on any one regex match; 0 means none
+	dialect      rule.Dialect  // regex dialect for rules without a dialect flag
+
+	mode         outputMode
+	backupSuffix string // with --write, keep the original file at path+backupSuffix
+
+	listMacros bool // print the macros from the config file instead of running
+	explain    bool // print the compiled rules instead of running
+}
+
+// parseOptions separates flags and input files from rule arguments.
+// Flags may appear anywhere before "--". Everything after "--" is an input path.
+// Rules never start with '-', so any argument that does is treated as a flag.
+func parseOptions(args []string) (*options, error) {
+	opts := &options{jobs: 1}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if arg == "--" {
+			opts.inputs = append(opts.inputs, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			opts.rules = append(opts.rules, arg)
+			opts.ruleArg = append(opts.ruleArg, i)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(arg, "=")
+		// takeValue returns the flag's value, either from "--flag=value" or
+		// from the following argument.
+		takeValue := func() (string, error) {
+			if hasValue {
+				return value, nil
+			}
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("%s requires a value", name)
+			}
+			i++
+			return args[i], nil
+		}
+		// noValue rejects "--flag=value" for flags that don't take one.
+		noValue := func() error {
+			if hasValue {
+				return fmt.Errorf("%s does not take a value", name)
+			}
+			return nil
+		}
+
+		var err error
+		switch name {
+		case "-f", "--file":
+			var path string
+			path, err = takeValue()
+			opts.scripts = append(opts.scripts, path)
+		case "--input":
+			var path string
+			path, err = takeValue()
+			opts.inputs = append(opts.inputs, path)
+		case "-r", "--recursive":
+			err = noValue()
+			opts.recursive = true
+		case "--include":
+			var glob string
+			glob, err = takeValue()
+			opts.walk.Include = append(opts.walk.Include, glob)
+		case "--exclude":
+			var glob string
+			glob, err = takeValue()
+			opts.walk.Exclude = append(opts.walk.Exclude, glob)
+		case "--hidden":
+			err = noValue()
+			opts.walk.Hidden = true
+		case "--no-ignore":
+			err = noValue()
+			opts.walk.NoIgnore = true
+		case "--binary":
+			var mode string
+			if mode, err = takeValue(); err == nil {
+				switch mode {
+				case "skip":
+					opts.binary = binarySkip
+				case "process":
+					opts.binary = binaryProcess
+				case "error":
+					opts.binary = binaryError
+				default:
+					err = fmt.Errorf("invalid --binary %q: must be skip, process or error", mode)
+				}
+			}
+		case "-j", "--jobs":
+			var n string
+			if n, err = takeValue(); err == nil {
+				opts.jobs, err = parseJobs(n)
+			}
+		case "--regex-timeout":
+			var d string
+			if d, err = takeValue(); err == nil {
+				opts.regexTimeout, err = parseTimeout(d)
+			}
+		case "--regex":
+			var name string
+			if name, err = takeValue(); err == nil {
+				if opts.dialect, err = rule.ParseDialect(name); err != nil {
+					err = fmt.Errorf("invalid --regex: %w", err)
+				}
+			}
+		case "--concat":
+			err = noValue()
+			opts.concat = true
+		case "--eol":
+			var eol string
+			if eol, err = takeValue(); err == nil {
+				switch eol {
+				case "lf":
+					opts.eol = lineio.LF
+				case "crlf":
+					opts.eol = lineio.CRLF
+				default:
+					err = fmt.Errorf("invalid --eol %q: must be lf or crlf", eol)
+				}
+			}
+		case "-z", "--null":
+			err = noValue()
+			opts.rs, opts.eol = "\x00", "\x00"
+		case "--rs":
+			var rs string
+			if rs, err = takeValue(); err == nil {
+				opts.rs, err = parseSeparator(name, rs)
+			}
+		case "--ors":
+			var ors string
+			if ors, err = takeValue(); err == nil {
+				opts.eol, err = parseSeparator(name, ors)
+			}
+		case "--macros":
+			err = noValue()
+			opts.listMacros = true
+		case "--explain":
+			err = noValue()
+			opts.explain = true
+		case "--paragraph":
+			err = noValue()
+			opts.paragraph = true
+		case "--write":
+			// The backup suffix is optional, so it is only accepted as
+			// "--write=SUFFIX" — the next argument is never consumed.
+			err = opts.setMode(modeWrite)
+			opts.backupSuffix = value
+		case "--diff":
+			if err = noValue(); err == nil {
+				err = opts.setMode(modeDiff)
+			}
+		case "--check":
+			if err = noValue(); err == nil {
+				err = opts.setMode(modeCheck)
+			}
+		default:
+			err = fmt.Errorf("unknown option: %s", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// As with sed -f, when rules come from a script, the arguments that
+	// would have been rules are inputs.
+	if len(opts.scripts) > 0 {
+		opts.inputs = append(opts.rules, opts.inputs...)
+		opts.rules, opts.ruleArg = nil, nil
+	}
+	if opts.paragraph && opts.rs != "" {
+		return nil, fmt.Errorf("--paragraph cannot be combined with a record separator")
+	}
+	if opts.concat && opts.mode != modePrint {
+		return nil, fmt.Errorf("--concat cannot be combined with %s", modeFlags[opts.mode])
+	}
+
+	return opts, nil
+}
+
+// parseSeparator decodes a record separator given on the command line.
+// Backslash escapes \n, \r, \t, \0 and \\ are recognized, so separators can
+// be written without shell quoting tricks.
+func parseSeparator(flag, value string) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(value); i++ {
+		ch := value[i]
+		if ch != '\\' || i+1 == len(value) {
+			sb.WriteByte(ch)
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case '0':
+			sb.WriteByte(0)
+		case '\\':
+			sb.WriteByte('\\')
+		default:
+			sb.WriteByte('\\')
+			sb.WriteByte(value[i])
+		}
+	}
+	if sb.Len() == 0 {
+		return "", fmt.Errorf("%s must not be empty", flag)
+	}
+	return sb.String(), nil
+}
+
+// parseJobs parses the value of -j. 0 means one job per CPU.
+func parseJobs(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid -j %q: must be a number of jobs, or 0 for one per CPU", value)
+	}
+	if n == 0 {
+		n = runtime.NumCPU()
+	}
+	return n, nil
+}
+
+// parseTimeout parses the value of --regex-timeout, a Go duration such as
+// "500ms" or "2s".
+func parseTimeout(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid --regex-timeout %q: must be a duration such as 500ms or 2s", value)
+	}
+	return d, nil
+}
+
+// setMode selects the output mode, rejecting a second, different mode.
+func (o *options) setMode(mode outputMode) error {
+	if o.mode != modePrint && o.mode != mode {
+		return fmt.Errorf("%s cannot be combined with %s", modeFlags[mode], modeFlags[o.mode])
+	}
+	o.mode = mode
+	return nil
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/colinta/ged/internal/rule"
+)
+
+func TestParseOptions_RulesOnly(t *testing.T) {
+	opts, err := parseOptions([]string{"s/a/b/", "sort"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(opts.rules, []string{"s/a/b/", "sort"}) {
+		t.Errorf("rules: got %v", opts.rules)
+	}
+	if len(opts.inputs) != 0 {
+		t.Errorf("inputs: got %v, want none", opts.inputs)
+	}
+}
+
+func TestParseOptions_FilesAfterDoubleDash(t *testing.T) {
+	opts, err := parseOptions([]string{"s/a/b/", "--", "a.txt", "--concat"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(opts.rules, []string{"s/a/b/"}) {
+		t.Errorf("rules: got %v", opts.rules)
+	}
+	// Everything after "--" is a path, even if it looks like a flag
+	if !reflect.DeepEqual(opts.inputs, []string{"a.txt", "--concat"}) {
+		t.Errorf("inputs: got %v", opts.inputs)
+	}
+	if opts.concat {
+		t.Error("expected concat=false")
+	}
+}
+
+func TestParseOptions_Input(t *testing.T) {
+	opts, err := parseOptions([]string{"--input", "a.txt", "--input=*.log", "p/x/", "--concat"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(opts.inputs, []string{"a.txt", "*.log"}) {
+		t.Errorf("inputs: got %v", opts.inputs)
+	}
+	if !reflect.DeepEqual(opts.rules, []string{"p/x/"}) {
+		t.Errorf("rules: got %v", opts.rules)
+	}
+	if !opts.concat {
+		t.Error("expected concat=true")
+	}
+}
+
+func TestParseOptions_InputMissingValue(t *testing.T) {
+	_, err := parseOptions([]string{"s/a/b/", "--input"})
+	if err == nil {
+		t.Error("expected error for --input without a value")
+	}
+}
+
+func TestParseOptions_Unknown(t *testing.T) {
+	_, err := parseOptions([]string{"--bogus", "s/a/b/"})
+	if err == nil {
+		t.Error("expected error for unknown option")
+	}
+}
+
+func TestParseOptions_Write(t *testing.T) {
+	opts, err := parseOptions([]string{"--write", "s/a/b/", "--", "a.txt"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.mode != modeWrite || opts.backupSuffix != "" {
+		t.Errorf("got mode=%v backupSuffix=%q, want write and empty", opts.mode, opts.backupSuffix)
+	}
+	// The rule after --write must not be taken as a backup suffix
+	if !reflect.DeepEqual(opts.rules, []string{"s/a/b/"}) {
+		t.Errorf("rules: got %v", opts.rules)
+	}
+}
+
+func TestParseOptions_WriteBackupSuffix(t *testing.T) {
+	opts, err := parseOptions([]string{"--write=.bak", "s/a/b/"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.mode != modeWrite || opts.backupSuffix != ".bak" {
+		t.Errorf("got mode=%v backupSuffix=%q, want write and .bak", opts.mode, opts.backupSuffix)
+	}
+}
+
+func TestParseOptions_Null(t *testing.T) {
+	opts, err := parseOptions([]string{"-z", "s/a/b/"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.rs != "\x00" || opts.eol != "\x00" {
+		t.Errorf("got rs=%q eol=%q, want NUL for both", opts.rs, opts.eol)
+	}
+}
+
+func TestParseOptions_RecordSeparators(t *testing.T) {
+	opts, err := parseOptions([]string{`--rs=\n---\n`, "--ors", `\t`, "s/a/b/"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.rs != "\n---\n" {
+		t.Errorf("rs: got %q, want %q", opts.rs, "\n---\n")
+	}
+	if opts.eol != "\t" {
+		t.Errorf("ors: got %q, want %q", opts.eol, "\t")
+	}
+}
+
+func TestParseSeparator(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{",", ","},
+		{`\0`, "\x00"},
+		{`\r\n`, "\r\n"},
+		{`a\\b`, `a\b`},
+		{`\x`, `\x`},
+		{`end\`, `end\`},
+	}
+	for _, tt := range tests {
+		got, err := parseSeparator("--rs", tt.in)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", tt.in, err)
+		}
+		if got != tt.want {
+			t.Errorf("parseSeparator(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := parseSeparator("--rs", ""); err == nil {
+		t.Error("expected error for empty separator")
+	}
+}
+
+func TestParseOptions_ParagraphWithSeparator(t *testing.T) {
+	_, err := parseOptions([]string{"--paragraph", "-z", "s/a/b/"})
+	if err == nil {
+		t.Error("expected error for --paragraph with -z")
+	}
+}
+
+func TestParseOptions_Recursive(t *testing.T) {
+	opts, err := parseOptions([]string{"-r", "--include", "*.go", "--exclude=vendor/**", "--no-ignore", "s/a/b/", "--", "."})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !opts.recursive || !opts.walk.NoIgnore || opts.walk.Hidden {
+		t.Errorf("got recursive=%v walk=%+v", opts.recursive, opts.walk)
+	}
+	if !reflect.DeepEqual(opts.walk.Include, []string{"*.go"}) || !reflect.DeepEqual(opts.walk.Exclude, []string{"vendor/**"}) {
+		t.Errorf("got include=%q exclude=%q", opts.walk.Include, opts.walk.Exclude)
+	}
+}
+
+func TestParseOptions_InvalidBinary(t *testing.T) {
+	if _, err := parseOptions([]string{"--binary=maybe", "s/a/b/"}); err == nil {
+		t.Error("expected error for invalid --binary")
+	}
+}
+
+func TestParseOptions_Jobs(t *testing.T) {
+	opts, err := parseOptions([]string{"s/a/b/"})
+	if err != nil || opts.jobs != 1 {
+		t.Errorf("default: got jobs=%d err=%v, want 1", opts.jobs, err)
+	}
+	opts, err = parseOptions([]string{"-j", "4", "s/a/b/"})
+	if err != nil || opts.jobs != 4 {
+		t.Errorf("-j 4: got jobs=%d err=%v, want 4", opts.jobs, err)
+	}
+	opts, err = parseOptions([]string{"--jobs=0", "s/a/b/"})
+	if err != nil || opts.jobs < 1 {
+		t.Errorf("--jobs=0: got jobs=%d err=%v, want one per CPU", opts.jobs, err)
+	}
+	if _, err := parseOptions([]string{"-j", "-1", "s/a/b/"}); err == nil {
+		t.Error("expected error for -j -1")
+	}
+}
+
+func TestParseOptions_RegexTimeout(t *testing.T) {
+	opts, err := parseOptions([]string{"--regex-timeout", "250ms", "s/a/b/"})
+	if err != nil || opts.regexTimeout != 250*time.Millisecond {
+		t.Errorf("got %v, %v; want 250ms", opts.regexTimeout, err)
+	}
+	for _, bad := range []string{"0s", "-1s", "5"} {
+		if _, err := parseOptions([]string{"--regex-timeout=" + bad, "s/a/b/"}); err == nil {
+			t.Errorf("expected error for --regex-timeout=%s", bad)
+		}
+	}
+}
+
+func TestParseOptions_Regex(t *testing.T) {
+	opts, err := parseOptions([]string{"--regex", "re2", "s/a/b/"})
+	if err != nil || opts.dialect != rule.RE2 {
+		t.Errorf("got %v, %v; want re2", opts.dialect, err)
+	}
+	opts, err = parseOptions([]string{"s/a/b/"})
+	if err != nil || opts.dialect != rule.ECMAScript {
+		t.Errorf("default: got %v, %v; want ecmascript", opts.dialect, err)
+	}
+	if _, err := parseOptions([]string{"--regex=pcre", "s/a/b/"}); err == nil {
+		t.Error("expected error for --regex=pcre")
+	}
+}
+
+func TestParseOptions_ScriptMakesArgsInputs(t *testing.T) {
+	opts, err := parseOptions([]string{"-f", "fix.ged", "a.txt", "--", "b.txt"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(opts.scripts, []string{"fix.ged"}) || len(opts.rules) != 0 {
+		t.Errorf("got scripts=%q rules=%q", opts.scripts, opts.rules)
+	}
+	if !reflect.DeepEqual(opts.inputs, []string{"a.txt", "b.txt"}) {
+		t.Errorf("got inputs %q, want [a.txt b.txt]", opts.inputs)
+	}
+}
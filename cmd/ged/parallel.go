@@ -0,0 +1,92 @@
+package main
+
+import "sync"
+
+// fileResult is the buffered outcome of processing one input.
+type fileResult struct {
+	output  []byte
+	matched bool
+	sep     string // the input's terminator, when printing its output
+}
+
+// forEachOrdered runs work for the indexes 0..n-1 on up to jobs goroutines
+// and passes each result to emit in index order, so what is emitted is the
+// same as for a sequential run. A result is emitted as soon as all earlier
+// ones have been. The first error in index order stops the run: no further
+// work is started, and forEachOrdered returns once work already running
+// has finished. With jobs <= 1 everything runs on the calling goroutine.
+//
+// The rules shared by concurrent work keep their per-document state on
+// each document's own LineContext, so one compiled program serves every
+// goroutine.
+func forEachOrdered[T any](n, jobs int, work func(i int) (T, error), emit func(T) error) error {
+	if jobs <= 1 {
+		for i := 0; i < n; i++ {
+			val, err := work(i)
+			if err != nil {
+				return err
+			}
+			if err := emit(val); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	type outcome struct {
+		val T
+		err error
+	}
+	results := make([]chan outcome, n)
+	for i := range results {
+		results[i] = make(chan outcome, 1)
+	}
+
+	// window bounds the results waiting to be emitted, so one slow input
+	// doesn't let the results of all the others pile up in memory.
+	window := make(chan struct{}, 2*jobs)
+	indexes := make(chan int)
+	done := make(chan struct{})
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer close(done)
+
+	go func() {
+		defer close(indexes)
+		for i := 0; i < n; i++ {
+			select {
+			case window <- struct{}{}:
+			case <-done:
+				return
+			}
+			select {
+			case indexes <- i:
+			case <-done:
+				return
+			}
+		}
+	}()
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				val, err := work(i)
+				results[i] <- outcome{val, err}
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		res := <-results[i]
+		<-window
+		if res.err != nil {
+			return res.err
+		}
+		if err := emit(res.val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
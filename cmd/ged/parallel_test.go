@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/colinta/ged/internal/engine"
+)
+
+func TestForEachOrdered(t *testing.T) {
+	for _, jobs := range []int{1, 4} {
+		var got []int
+		err := forEachOrdered(20, jobs, func(i int) (int, error) {
+			time.Sleep(time.Duration(rand.Intn(2000)) * time.Microsecond)
+			return i * i, nil
+		}, func(v int) error {
+			got = append(got, v)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("jobs=%d: unexpected error: %v", jobs, err)
+		}
+		for i, v := range got {
+			if v != i*i {
+				t.Fatalf("jobs=%d: results out of order: %v", jobs, got)
+			}
+		}
+		if len(got) != 20 {
+			t.Errorf("jobs=%d: got %d results, want 20", jobs, len(got))
+		}
+	}
+}
+
+func TestForEachOrdered_FirstErrorInOrder(t *testing.T) {
+	errs := map[int]error{3: errors.New("three"), 7: errors.New("seven")}
+	for _, jobs := range []int{1, 4} {
+		var got []int
+		err := forEachOrdered(10, jobs, func(i int) (int, error) {
+			if i == 3 {
+				// Finish after the later failure, which must still lose
+				time.Sleep(5 * time.Millisecond)
+			}
+			return i, errs[i]
+		}, func(v int) error {
+			got = append(got, v)
+			return nil
+		})
+		if err != errs[3] {
+			t.Errorf("jobs=%d: got error %v, want %v", jobs, err, errs[3])
+		}
+		if !reflect.DeepEqual(got, []int{0, 1, 2}) {
+			t.Errorf("jobs=%d: emitted %v, want [0 1 2]", jobs, got)
+		}
+	}
+}
+
+func TestRun_ParallelMatchesSequential(t *testing.T) {
+	files := map[string]string{}
+	for i := 0; i < 30; i++ {
+		files[fmt.Sprintf("f%02d.txt", i)] = fmt.Sprintf("skip\nstart\nx %d\nbegin\nx %d\nend\nlast x", i%7, i%3)
+	}
+	dir := writeFiles(t, files)
+	var paths []string
+	for i := 0; i < 30; i++ {
+		paths = append(paths, filepath.Join(dir, fmt.Sprintf("f%02d.txt", i)))
+	}
+
+	rules := [][]string{
+		{"on/start/", "s/x/X/"},
+		{"between/begin/end/", "{", "sort", "}"},
+		{"if/[0-9]/", "{", "reverse", "}", "s:2:two"},
+	}
+	modes := [][]string{nil, {"--diff"}, {"--check"}}
+
+	for _, r := range rules {
+		for _, mode := range modes {
+			runWith := func(jobs string) (string, string, error) {
+				args := append([]string{"-j", jobs}, mode...)
+				args = append(append(args, r...), "--")
+				args = append(args, paths...)
+				out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+				err := run(args, strings.NewReader(""), out, errOut)
+				return out.String(), errOut.String(), err
+			}
+			wantOut, wantErrOut, wantErr := runWith("1")
+			gotOut, gotErrOut, gotErr := runWith("8")
+			if gotOut != wantOut || gotErrOut != wantErrOut || gotErr != wantErr {
+				t.Errorf("%q %q: -j 8 differs from -j 1:\nstdout %q\nwant   %q\nstderr %q\nwant   %q\nerr %v, want %v",
+					mode, r, gotOut, wantOut, gotErrOut, wantErrOut, gotErr, wantErr)
+			}
+		}
+	}
+}
+
+func TestRun_ParallelWrite(t *testing.T) {
+	files := map[string]string{}
+	for i := 0; i < 20; i++ {
+		files[fmt.Sprintf("f%02d.txt", i)] = "foo\n"
+	}
+	dir := writeFiles(t, files)
+
+	err := run([]string{"-j", "4", "-r", "--write", "s/foo/bar/", "--", dir}, strings.NewReader(""), io.Discard, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name := range files {
+		if got, _ := os.ReadFile(filepath.Join(dir, name)); string(got) != "bar\n" {
+			t.Errorf("%s: got %q, want %q", name, got, "bar\n")
+		}
+	}
+}
+
+func TestRun_ParallelReportsFirstError(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a": "x\n", "c": "x\n"})
+	out := &bytes.Buffer{}
+
+	err := run([]string{"-j", "2", "p/x/", "--", filepath.Join(dir, "a"), filepath.Join(dir, "missing"), filepath.Join(dir, "c")}, strings.NewReader(""), out, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("got %v, want an error about the missing file", err)
+	}
+	// Output from inputs before the failing one is still printed
+	if out.String() != "x\n" {
+		t.Errorf("got %q, want %q", out.String(), "x\n")
+	}
+}
+
+func TestRun_ChunkedMatchesSequential(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i < 3*engine.ChunkSize+17; i++ {
+		fmt.Fprintf(&sb, "line %d\n", i)
+	}
+	big := sb.String()
+
+	inputs := map[string]string{
+		"lf":                 big,
+		"no final newline":   strings.TrimSuffix(big, "\n"),
+		"crlf":               strings.ReplaceAll(big, "\n", "\r\n"),
+		"mixed line endings": strings.ReplaceAll(big, "5\n", "5\r\n"),
+		"final line deleted": big + "drop\n",
+	}
+	rules := [][]string{
+		{"d/drop/", "s/(\\d+)$/<$1>/", "p/[02468]>/"},
+		{"if/1/", "{", "s/line/LINE/", "}"},
+		// Order-dependent rules fall back to sequential processing
+		{"on/line 5000/", "s:2-3:x"},
+	}
+
+	for name, input := range inputs {
+		// Only a file is chunked, not stdin
+		path := filepath.Join(t.TempDir(), "input.txt")
+		if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range rules {
+			runWith := func(jobs string) string {
+				out := &bytes.Buffer{}
+				args := append(append([]string{"-j", jobs}, r...), "--", path)
+				err := run(args, strings.NewReader(""), out, io.Discard)
+				if err != nil {
+					t.Fatalf("%s %q -j %s: unexpected error: %v", name, r, jobs, err)
+				}
+				return out.String()
+			}
+			if got, want := runWith("4"), runWith("1"); got != want {
+				t.Errorf("%s %q: -j 4 output differs from -j 1", name, r)
+			}
+		}
+	}
+}
+
+// signalWriter reports the first write on a channel.
+type signalWriter struct {
+	bytes.Buffer
+	wrote chan struct{}
+}
+
+func (w *signalWriter) Write(p []byte) (int, error) {
+	n, err := w.Buffer.Write(p)
+	select {
+	case w.wrote <- struct{}{}:
+	default:
+	}
+	return n, err
+}
+
+func TestRun_ParallelStdinStreams(t *testing.T) {
+	pr, pw := io.Pipe()
+	out := &signalWriter{wrote: make(chan struct{}, 1)}
+	done := make(chan error, 1)
+	go func() { done <- run([]string{"-j", "4", "s/a/A/"}, pr, out, io.Discard) }()
+
+	// The first line's output comes before the input ends
+	if _, err := io.WriteString(pw, "a\n"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-out.wrote:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no output for the first line before the input ended")
+	}
+	pw.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "A\n" {
+		t.Errorf("got %q, want %q", out.String(), "A\n")
+	}
+}
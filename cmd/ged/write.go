@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// writeInPlace runs the program over a file and replaces the file with the
+// result. Files whose output is unchanged are not touched, so their mtime
+// stays as it is. If backupSuffix is set, the original content is kept at
+// path+backupSuffix. Gzip files are recompressed; bzip2 files are rejected.
+// Reports whether any rule matched.
+func (p *program) writeInPlace(path string, backupSuffix string) (bool, error) {
+	// Write through symlinks rather than replacing the link with a file.
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return false, err
+	}
+
+	info, err := os.Stat(target)
+	if err != nil {
+		return false, err
+	}
+	raw, err := os.ReadFile(target)
+	if err != nil {
+		return false, err
+	}
+
+	// Compressed files are edited decompressed and then recompressed.
+	// There is no bzip2 compressor in the standard library, so those
+	// files are read-only.
+	comp := detectCompression(target, raw)
+	if comp == bzipped {
+		return false, fmt.Errorf("cannot write %s: --write does not support bzip2 files", path)
+	}
+	in := input{name: target, open: func() (io.ReadCloser, error) {
+		return decompress(target, io.NopCloser(bytes.NewReader(raw)))
+	}}
+	original, result, matched, err := p.transform(in)
+	if err != nil {
+		return false, err
+	}
+	if bytes.Equal(result, original) {
+		return matched, nil
+	}
+	if comp == gzipped {
+		if result, err = recompress(raw, result); err != nil {
+			return false, fmt.Errorf("error compressing %s: %w", path, err)
+		}
+	}
+
+	if backupSuffix != "" {
+		if err := writeFileAtomic(target+backupSuffix, raw, info); err != nil {
+			return false, fmt.Errorf("error writing backup of %s: %w", path, err)
+		}
+	}
+	if err := writeFileAtomic(target, result, info); err != nil {
+		return false, fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return matched, nil
+}
+
+// writeFileAtomic replaces path with data. The data is written to a temp file
+// in the same directory, which is then renamed into place, so readers never
+// see a partially written file. The mode and, where the platform allows,
+// the ownership from info are applied to the new file.
+func writeFileAtomic(path string, data []byte, info os.FileInfo) error {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+base+".ged-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	// Clean up the temp file on any failure; after a successful rename
+	// this is a no-op error that is ignored.
+	defer os.Remove(tmpName)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+	// Ownership can only be kept when permitted (e.g. running as root);
+	// failing to chown is not an error.
+	chownLike(tmp, info)
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpName, path)
+}
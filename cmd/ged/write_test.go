@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun_WriteInPlace(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.txt": "foo\nbar\n"})
+	path := filepath.Join(dir, "a.txt")
+	out := &bytes.Buffer{}
+
+	err := run([]string{"--write", "s/foo/FOO/", "--", path}, strings.NewReader(""), out, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if out.String() != "" {
+		t.Errorf("expected no stdout, got %q", out.String())
+	}
+	got, _ := os.ReadFile(path)
+	if string(got) != "FOO\nbar\n" {
+		t.Errorf("file: got %q, want %q", got, "FOO\nbar\n")
+	}
+
+	// No temp files left behind
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("expected only a.txt in dir, got %d entries", len(entries))
+	}
+}
+
+func TestRun_WriteKeepsMode(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"run.sh": "echo foo\n"})
+	path := filepath.Join(dir, "run.sh")
+	if err := os.Chmod(path, 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	err := run([]string{"--write", "s/foo/bar/", "--", path}, strings.NewReader(""), io.Discard, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o750 {
+		t.Errorf("mode: got %v, want %v", info.Mode().Perm(), os.FileMode(0o750))
+	}
+}
+
+func TestRun_WriteBackup(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.txt": "foo\n"})
+	path := filepath.Join(dir, "a.txt")
+
+	err := run([]string{"--write=.bak", "s/foo/bar/", "--", path}, strings.NewReader(""), io.Discard, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, _ := os.ReadFile(path)
+	if string(got) != "bar\n" {
+		t.Errorf("file: got %q, want %q", got, "bar\n")
+	}
+	backup, err := os.ReadFile(path + ".bak")
+	if err != nil {
+		t.Fatalf("backup not written: %v", err)
+	}
+	if string(backup) != "foo\n" {
+		t.Errorf("backup: got %q, want %q", backup, "foo\n")
+	}
+}
+
+func TestRun_WriteUnchangedFileNotTouched(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.txt": "foo\n"})
+	path := filepath.Join(dir, "a.txt")
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	err := run([]string{"--write=.bak", "s/nomatch/x/", "--", path}, strings.NewReader(""), io.Discard, io.Discard)
+	if err != errNoMatch {
+		t.Fatalf("got error %v, want %v", err, errNoMatch)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(past) {
+		t.Errorf("mtime changed: got %v, want %v", info.ModTime(), past)
+	}
+	if _, err := os.Stat(path + ".bak"); !os.IsNotExist(err) {
+		t.Error("expected no backup for unchanged file")
+	}
+}
+
+func TestRun_WriteMixedLineEndings(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"mixed.txt": "a\r\nb\nc\n", "other.txt": "a\r\nb\nc\n"})
+	mixed := filepath.Join(dir, "mixed.txt")
+	other := filepath.Join(dir, "other.txt")
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(mixed, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	err := run([]string{"--write", "s/zzz/y/", "--", mixed}, strings.NewReader(""), io.Discard, io.Discard)
+	if err != errNoMatch {
+		t.Fatalf("got error %v, want %v", err, errNoMatch)
+	}
+	if info, err := os.Stat(mixed); err != nil || !info.ModTime().Equal(past) {
+		t.Errorf("unmatched file was rewritten: %v, %v", info, err)
+	}
+
+	// Lines a rule changes keep their terminators too.
+	if err := run([]string{"--write", "s/b/x/", "--", other}, strings.NewReader(""), io.Discard, io.Discard); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, _ := os.ReadFile(other); string(got) != "a\r\nx\nc\n" {
+		t.Errorf("got %q, want %q", got, "a\r\nx\nc\n")
+	}
+}
+
+func TestRun_WriteThroughSymlink(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"real.txt": "foo\n"})
+	link := filepath.Join(dir, "link.txt")
+	if err := os.Symlink("real.txt", link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	err := run([]string{"--write", "s/foo/bar/", "--", link}, strings.NewReader(""), io.Discard, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	info, err := os.Lstat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		t.Error("symlink was replaced by a regular file")
+	}
+	got, _ := os.ReadFile(filepath.Join(dir, "real.txt"))
+	if string(got) != "bar\n" {
+		t.Errorf("target: got %q, want %q", got, "bar\n")
+	}
+}
+
+func TestRun_WriteRequiresFiles(t *testing.T) {
+	err := run([]string{"--write", "s/foo/bar/"}, strings.NewReader("foo"), io.Discard, io.Discard)
+	if err == nil {
+		t.Error("expected error for --write with stdin")
+	}
+}
+
+func TestRun_WriteByteExact(t *testing.T) {
+	// CRLF endings and a missing final newline survive an in-place edit
+	dir := writeFiles(t, map[string]string{"win.ini": "[a]\r\nkey=old\r\nend"})
+	path := filepath.Join(dir, "win.ini")
+
+	err := run([]string{"--write", "s/old/new/", "--", path}, strings.NewReader(""), io.Discard, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, _ := os.ReadFile(path)
+	if string(got) != "[a]\r\nkey=new\r\nend" {
+		t.Errorf("file: got %q", got)
+	}
+}
+
+func TestRun_WriteRecursive(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.go":         "foo\n",
+		"sub/b.go":     "foo\n",
+		"sub/c.txt":    "foo\n",
+		"build/d.go":   "foo\n",
+		".gitignore":   "build/\n",
+		".hidden/e.go": "foo\n",
+	})
+
+	err := run([]string{"-r", "--write", "--include=*.go", "s/foo/bar/", "--", dir}, strings.NewReader(""), io.Discard, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]string{
+		"a.go":         "bar\n",
+		"sub/b.go":     "bar\n",
+		"sub/c.txt":    "foo\n",
+		"build/d.go":   "foo\n",
+		".hidden/e.go": "foo\n",
+	}
+	for name, content := range want {
+		got, _ := os.ReadFile(filepath.Join(dir, name))
+		if string(got) != content {
+			t.Errorf("%s: got %q, want %q", name, got, content)
+		}
+	}
+}
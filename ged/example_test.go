@@ -0,0 +1,99 @@
+package ged_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/colinta/ged/ged"
+	"golang.org/x/text/transform"
+)
+
+func ExampleCompile() {
+	prog, err := ged.Compile(`
+# Promote the headings and drop blank lines
+if/^## / { s/## /# / }
+d/^\s*$/
+`)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	out, _ := prog.Transform("## One\n\ntext\n## Two\n")
+	fmt.Print(out)
+	// Output:
+	// # One
+	// text
+	// # Two
+}
+
+func ExampleCompile_error() {
+	_, err := ged.Compile("sort\nfrob/x/")
+	fmt.Println(err)
+	// Output:
+	// 2:1: unknown command "frob"
+}
+
+func ExampleProgram_Run() {
+	prog := ged.MustCompile("s/(\\w+)=(\\w+)/$2=$1/; sort")
+	err := prog.Run(strings.NewReader("b=2\na=1\n"), os.Stdout)
+	if err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// 1=a
+	// 2=b
+}
+
+func ExampleProgram_Transform() {
+	prog := ged.MustCompile("s/colour/color/g")
+	out, err := prog.Transform("colour, colours")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("%q\n", out)
+	// Output:
+	// "color, colors"
+}
+
+func ExampleNewReader() {
+	prog := ged.MustCompile("d/^#/; s/^/> /")
+	r := ged.NewReader(strings.NewReader("# header\nhello\nworld\n"), prog)
+	if _, err := io.Copy(os.Stdout, r); err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// > hello
+	// > world
+}
+
+func ExampleNewWriter() {
+	prog := ged.MustCompile("s/secret=\\S+/secret=***/g")
+	w := ged.NewWriter(os.Stdout, prog)
+	fmt.Fprintln(w, "user=me secret=hunter2")
+	fmt.Fprint(w, "secret=swordfish")
+	// Close processes the final line, which has no newline
+	if err := w.Close(); err != nil {
+		fmt.Println(err)
+	}
+	fmt.Println()
+	// Output:
+	// user=me secret=***
+	// secret=***
+}
+
+func ExampleNewTransformer() {
+	prog := ged.MustCompile("reverse")
+	out, _, err := transform.String(ged.NewTransformer(prog), "one\ntwo\nthree\n")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Print(out)
+	// Output:
+	// three
+	// two
+	// one
+}
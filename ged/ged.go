@@ -0,0 +1,140 @@
+// Package ged runs ged scripts from Go programs.
+//
+// A script uses the same syntax as a ged script file or rule argument:
+//
+//	prog, err := ged.Compile(`if/^## / { s/## /# / }; d/^\s*$/`)
+//	if err != nil {
+//		return err
+//	}
+//	out, err := prog.Transform(text)
+//
+// Input is split into lines ending in LF or CRLF, and each line's output
+// ends the way the line did, as with the ged command. Lines a rule adds,
+// and the output of document rules such as sort, end the way the input's
+// first line does. A missing final newline stays missing. Besides Run and
+// Transform, a program can be used as a streaming filter with NewReader,
+// NewWriter and NewTransformer.
+//
+// Use RunContext to stop a long run early. A regex that backtracks badly
+// on some input can be limited with WithRegexTimeout; a match that runs out
+// of time is an error naming the rule and the line.
+//
+// A Program is safe for concurrent use by multiple goroutines. Each call to
+// Run or Transform starts from a clean state, so line numbers, control
+// rules such as on and off, and between ranges never carry over from one
+// call to the next.
+package ged
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/colinta/ged/internal/engine"
+	"github.com/colinta/ged/internal/lineio"
+	"github.com/colinta/ged/internal/parser"
+	"github.com/colinta/ged/internal/rule"
+)
+
+// Error is a syntax error in a script, giving the line and column of the
+// problem; its Caret method shows the line with the problem marked.
+// Compile returns errors of this type.
+type Error = parser.Error
+
+// Program is a compiled script.
+type Program struct {
+	rules *engine.Program
+}
+
+// Option configures Compile.
+type Option func(*options)
+
+type options struct {
+	parseOpts []parser.Option
+}
+
+// WithRegexTimeout limits the time any one regex match in the script may
+// take. A match that runs longer fails the run with an error.
+func WithRegexTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.parseOpts = append(o.parseOpts, parser.WithMatchTimeout(d))
+	}
+}
+
+// Dialect selects the regex engine a script's patterns are compiled with.
+type Dialect = rule.Dialect
+
+// The regex dialects. ECMAScript is the default; RE2 matches in linear
+// time but has no backreferences or lookaround.
+const (
+	ECMAScript = rule.ECMAScript
+	DotNet     = rule.DotNet
+	RE2        = rule.RE2
+)
+
+// WithDialect compiles the script's patterns in dialect d, except in rules
+// that choose their own with a dialect flag (e, n or r).
+func WithDialect(d Dialect) Option {
+	return func(o *options) {
+		o.parseOpts = append(o.parseOpts, parser.WithDialect(d))
+	}
+}
+
+// Compile parses a script. Rules may be separated by newlines or ';', and
+// '#' starts a comment. A script without rules is an error.
+func Compile(script string, opts ...Option) (*Program, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	parsed, err := parser.ParseScript("", script, o.parseOpts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(parsed) == 0 {
+		return nil, errors.New("no rules in script")
+	}
+	rules, err := engine.Compile(parsed)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{rules: rules}, nil
+}
+
+// MustCompile is like Compile but panics if the script cannot be compiled.
+// It simplifies initializing global variables holding programs.
+func MustCompile(script string) *Program {
+	prog, err := Compile(script)
+	if err != nil {
+		panic("ged: Compile(" + strconv.Quote(script) + "): " + err.Error())
+	}
+	return prog
+}
+
+// Run reads all of r, applies the program to it as one document, and
+// writes the result to w. If the program has no document rules (such as
+// sort or reverse), each line's output is written before the next line is
+// read, so Run can be used on a stream that does not end.
+func (p *Program) Run(r io.Reader, w io.Writer) error {
+	return p.RunContext(context.Background(), r, w)
+}
+
+// RunContext is like Run, but stops at the next line once ctx is done and
+// returns an error wrapping ctx.Err() that gives the line it stopped at.
+func (p *Program) RunContext(ctx context.Context, r io.Reader, w io.Writer) error {
+	lineCtx := &rule.LineContext{Context: ctx}
+	records := engine.ReaderRecords(lineio.NewReader(r))
+	return p.rules.Process(records, lineCtx, w, lineio.LF)
+}
+
+// Transform applies the program to s and returns the result.
+func (p *Program) Transform(s string) (string, error) {
+	var sb strings.Builder
+	if err := p.Run(strings.NewReader(s), &sb); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
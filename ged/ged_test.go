@@ -0,0 +1,120 @@
+package ged
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"unknown command", "s/a/b/\n  zap", "2:3: unknown command \"zap\""},
+		{"empty", "# nothing here\n", "no rules in script"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(tt.script)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("got %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompile_ErrorType(t *testing.T) {
+	_, err := Compile("if/x/ sort")
+	var gerr *Error
+	if !errors.As(err, &gerr) {
+		t.Fatalf("got %T, want *Error", err)
+	}
+	if gerr.Line != 1 || gerr.Col != 7 {
+		t.Errorf("got %d:%d, want 1:7", gerr.Line, gerr.Col)
+	}
+}
+
+func TestMustCompile_Panics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	MustCompile("zap")
+}
+
+func TestProgram_StateDoesNotCarryOver(t *testing.T) {
+	// Control rules and line numbers start over on each call
+	prog := MustCompile("off/^-/; d:1")
+	for i := 0; i < 2; i++ {
+		got, err := prog.Transform("a\nb\n-\nc\n")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != "b\n" {
+			t.Errorf("call %d: got %q, want %q", i+1, got, "b\n")
+		}
+	}
+}
+
+func TestProgram_RunContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := MustCompile("s/a/b/").RunContext(ctx, strings.NewReader("a\n"), io.Discard)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestWithRegexTimeout(t *testing.T) {
+	prog, err := Compile("p/x/; s/(a+)+$/x/", WithRegexTimeout(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = prog.Transform("x\nx" + strings.Repeat("a", 40) + "!\n")
+	if err == nil || !strings.Contains(err.Error(), "line 2: s/(a+)+$/x/:") {
+		t.Errorf("got %v, want a timeout at line 2 in s/(a+)+$/x/", err)
+	}
+}
+
+func TestWithDialect(t *testing.T) {
+	if _, err := Compile(`p/(a)\1/`, WithDialect(RE2)); err == nil {
+		t.Error("expected error for a backreference in RE2")
+	}
+	prog, err := Compile(`s/(?<word>\w+)/[${word}]/g`, WithDialect(RE2))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, _ := prog.Transform("a bc\n"); got != "[a] [bc]\n" {
+		t.Errorf("got %q, want %q", got, "[a] [bc]\n")
+	}
+}
+
+func TestProgram_Concurrent(t *testing.T) {
+	prog := MustCompile("on/start/; s/x/y/g; sort")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				in := fmt.Sprintf("skip %d\nstart\nx%d\nax\n", j, j)
+				want := fmt.Sprintf("ay\nstart\ny%d\n", j)
+				got, err := prog.Transform(in)
+				if err != nil || got != want {
+					t.Errorf("got %q, %v; want %q", got, err, want)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
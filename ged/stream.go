@@ -0,0 +1,217 @@
+package ged
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/colinta/ged/internal/engine"
+	"github.com/colinta/ged/internal/lineio"
+	"github.com/colinta/ged/internal/rule"
+	"golang.org/x/text/transform"
+)
+
+// NewReader returns a reader of the output of prog applied to the text read
+// from r. If prog has no document rules, input is only read as far as
+// needed to produce the output asked for, a line at a time; otherwise all
+// of r is read before the first byte of output.
+//
+// The reader is not safe for concurrent use, but any number of readers can
+// share prog.
+func NewReader(r io.Reader, prog *Program) io.Reader {
+	out := &reader{lines: lineio.NewReader(r)}
+	out.stream = prog.rules.NewStream(&rule.LineContext{}, &out.buf, lineio.LF)
+	return out
+}
+
+type reader struct {
+	lines  *lineio.Reader
+	stream *engine.Stream
+	buf    bytes.Buffer // output not yet read
+	err    error        // returned once buf is drained
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 && r.err == nil {
+		line, err := r.lines.ReadLine()
+		switch {
+		case err == io.EOF:
+			r.err = r.stream.Close()
+			if r.err == nil {
+				r.err = io.EOF
+			}
+		case err != nil:
+			r.err = err
+		default:
+			r.err = r.stream.Add(line, r.lines.Terminator(), r.lines.End())
+		}
+	}
+	if r.buf.Len() > 0 {
+		return r.buf.Read(p)
+	}
+	return 0, r.err
+}
+
+// NewWriter returns a writer that applies prog to the text written to it
+// and writes the output to w. If prog has no document rules, the output
+// for each line is written to w as soon as the line is complete; otherwise
+// nothing is written until Close. Close must be called to process a final
+// line without a terminator and to finish the document; it does not close w.
+//
+// The writer is not safe for concurrent use, but any number of writers can
+// share prog.
+func NewWriter(w io.Writer, prog *Program) io.WriteCloser {
+	return &writer{push: newPushStream(prog, w)}
+}
+
+type writer struct {
+	push *pushStream
+	err  error // the first error, returned from then on
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	if w.err = w.push.write(p); w.err != nil {
+		return 0, w.err
+	}
+	return len(p), nil
+}
+
+func (w *writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	w.err = w.push.close()
+	if w.err != nil {
+		return w.err
+	}
+	w.err = errClosed
+	return nil
+}
+
+// errClosed is returned by writes to a closed writer.
+var errClosed = errors.New("ged: write to closed writer")
+
+// NewTransformer returns a golang.org/x/text/transform.Transformer that
+// applies prog, for use with transform.NewReader, transform.NewWriter or
+// transform.String. As with NewWriter, output is produced a line at a time
+// unless prog has document rules.
+func NewTransformer(prog *Program) transform.Transformer {
+	t := &transformer{prog: prog}
+	t.Reset()
+	return t
+}
+
+type transformer struct {
+	prog   *Program
+	push   *pushStream
+	out    bytes.Buffer // output not yet copied to dst
+	closed bool         // the end of input has been processed
+}
+
+func (t *transformer) Reset() {
+	t.out.Reset()
+	t.push = newPushStream(t.prog, &t.out)
+	t.closed = false
+}
+
+func (t *transformer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	// Input is taken a line at a time, and only while there is no output
+	// waiting for room in dst, so that out stays small when streaming.
+	for {
+		nDst += copy(dst[nDst:], t.out.Next(len(dst)-nDst))
+		if t.out.Len() > 0 {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		if nSrc == len(src) {
+			break
+		}
+		end := len(src)
+		if i := bytes.IndexByte(src[nSrc:], '\n'); i >= 0 {
+			end = nSrc + i + 1
+		}
+		if err := t.push.write(src[nSrc:end]); err != nil {
+			return nDst, nSrc, err
+		}
+		nSrc = end
+	}
+
+	if atEOF && !t.closed {
+		t.closed = true
+		if err := t.push.close(); err != nil {
+			return nDst, nSrc, err
+		}
+		nDst += copy(dst[nDst:], t.out.Next(len(dst)-nDst))
+		if t.out.Len() > 0 {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+	}
+	return nDst, nSrc, nil
+}
+
+// pushStream applies a program to text that is handed to it in pieces,
+// rather than read from an io.Reader. Complete lines are passed through a
+// lineio.Reader, so they are split and terminated exactly as Run would.
+type pushStream struct {
+	stream  *engine.Stream
+	lines   *lineio.Reader // reads from in
+	in      bytes.Buffer   // complete lines not yet read
+	queued  int            // number of lines in in
+	partial []byte         // the start of a line whose end has not arrived
+}
+
+func newPushStream(prog *Program, w io.Writer) *pushStream {
+	s := &pushStream{stream: prog.rules.NewStream(&rule.LineContext{}, w, lineio.LF)}
+	s.lines = lineio.NewReader(&s.in)
+	return s
+}
+
+// write processes every line that p completes.
+func (s *pushStream) write(p []byte) error {
+	for {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			s.partial = append(s.partial, p...)
+			return nil
+		}
+		s.in.Write(s.partial)
+		s.in.Write(p[:i+1])
+		s.partial = s.partial[:0]
+		s.queued++
+		p = p[i+1:]
+		if err := s.flush(); err != nil {
+			return err
+		}
+	}
+}
+
+// close processes a final line without a terminator, if any, and ends
+// the document.
+func (s *pushStream) close() error {
+	if len(s.partial) > 0 {
+		s.in.Write(s.partial)
+		s.partial = nil
+		s.queued++
+	}
+	if err := s.flush(); err != nil {
+		return err
+	}
+	return s.stream.Close()
+}
+
+// flush processes the queued lines. The reader is only asked for a line
+// when a whole one is queued, so it never sees the end of in early.
+func (s *pushStream) flush() error {
+	for ; s.queued > 0; s.queued-- {
+		line, err := s.lines.ReadLine()
+		if err != nil {
+			return err
+		}
+		if err := s.stream.Add(line, s.lines.Terminator(), s.lines.End()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package ged
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"golang.org/x/text/transform"
+)
+
+// streamScripts cover streaming and document programs.
+var streamScripts = []string{"s/a/A/g; d/^x/", "sort; s/a/A/"}
+
+// streamInputs cover line endings and a missing final newline.
+var streamInputs = []string{"", "b\na\nx\n", "b\r\na\r\n", "b\r\na\nx", "b\na", "\n\n"}
+
+func TestNewReader_MatchesTransform(t *testing.T) {
+	for _, script := range streamScripts {
+		prog := MustCompile(script)
+		for _, in := range streamInputs {
+			want, _ := prog.Transform(in)
+			got, err := io.ReadAll(NewReader(iotest.OneByteReader(strings.NewReader(in)), prog))
+			if err != nil || string(got) != want {
+				t.Errorf("%s on %q: got %q, %v; want %q", script, in, got, err, want)
+			}
+		}
+	}
+}
+
+func TestNewReader_Streams(t *testing.T) {
+	// The first line's output is available before the input fails
+	src := io.MultiReader(strings.NewReader("a\n"), iotest.ErrReader(errors.New("boom")))
+	r := NewReader(src, MustCompile("s/a/A/"))
+
+	buf := make([]byte, 10)
+	n, err := r.Read(buf)
+	if err != nil || string(buf[:n]) != "A\n" {
+		t.Fatalf("got %q, %v; want %q", buf[:n], err, "A\n")
+	}
+	if _, err := r.Read(buf); err == nil || err.Error() != "boom" {
+		t.Errorf("got %v, want the read error", err)
+	}
+}
+
+func TestNewWriter_MatchesTransform(t *testing.T) {
+	for _, script := range streamScripts {
+		prog := MustCompile(script)
+		for _, in := range streamInputs {
+			want, _ := prog.Transform(in)
+			var out bytes.Buffer
+			w := NewWriter(&out, prog)
+			// One byte at a time, so lines arrive in pieces
+			for i := 0; i < len(in); i++ {
+				if _, err := w.Write([]byte{in[i]}); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != want {
+				t.Errorf("%s on %q: got %q, want %q", script, in, out.String(), want)
+			}
+		}
+	}
+}
+
+func TestNewWriter_StreamsLines(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out, MustCompile("s/a/A/"))
+
+	io.WriteString(w, "a\na")
+	if out.String() != "A\n" {
+		t.Errorf("after one line: got %q, want %q", out.String(), "A\n")
+	}
+	w.Close()
+	if out.String() != "A\nA" {
+		t.Errorf("after Close: got %q, want %q", out.String(), "A\nA")
+	}
+	if _, err := io.WriteString(w, "a\n"); err == nil {
+		t.Error("expected an error writing after Close")
+	}
+}
+
+func TestNewTransformer_MatchesTransform(t *testing.T) {
+	for _, script := range streamScripts {
+		prog := MustCompile(script)
+		tr := NewTransformer(prog)
+		for _, in := range streamInputs {
+			want, _ := prog.Transform(in)
+			got, _, err := transform.String(tr, in)
+			if err != nil || got != want {
+				t.Errorf("%s on %q: got %q, %v; want %q", script, in, got, err, want)
+			}
+		}
+	}
+}
+
+func TestNewTransformer_SmallBuffers(t *testing.T) {
+	// Lines longer than the transform package's buffers, read a byte at
+	// a time
+	long := strings.Repeat("a", 10000)
+	in := long + "\n" + long
+	want := strings.Repeat("A", 10000) + "\n" + strings.Repeat("A", 10000)
+
+	r := transform.NewReader(iotest.OneByteReader(strings.NewReader(in)), NewTransformer(MustCompile("s/a/A/g")))
+	got, err := io.ReadAll(r)
+	if err != nil || string(got) != want {
+		t.Errorf("got %d bytes, %v; want %d bytes", len(got), err, len(want))
+	}
+}
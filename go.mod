@@ -3,3 +3,5 @@ module github.com/colinta/ged
 go 1.25.7
 
 require github.com/dlclark/regexp2 v1.11.5
+
+require golang.org/x/text v0.29.0
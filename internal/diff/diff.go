@@ -0,0 +1,111 @@
+// Package diff computes line-based diffs and formats them as unified diffs.
+package diff
+
+// Op is the kind of change an Edit describes.
+type Op int
+
+const (
+	Equal  Op = iota // line is in both a and b
+	Delete           // line is only in a
+	Insert           // line is only in b
+)
+
+// Edit is one step of a line diff. A and B are the 0-indexed positions in
+// a and b: for Equal both lines exist, for Delete only a[A] exists and B is
+// where it would have been in b, and for Insert only b[B] exists and A is
+// where it would go in a.
+type Edit struct {
+	Op Op
+	A  int
+	B  int
+}
+
+// Lines computes the shortest edit script that turns a into b, using
+// Myers' O(ND) algorithm. Edits are returned in order.
+func Lines(a, b []string) []Edit {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+
+	// trace[d] holds the furthest x reached on diagonals -d..d after step d,
+	// indexed by k+d. Only that window is kept, so memory is O(D²) rather
+	// than O(D·(N+M)).
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // move down: insert from b
+			} else {
+				x = v[offset+k-1] + 1 // move right: delete from a
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				trace = append(trace, snapshot(v, offset, d))
+				return backtrack(trace, n, m)
+			}
+		}
+		trace = append(trace, snapshot(v, offset, d))
+	}
+	// Unreachable: the loop always finds a path by d == n+m.
+	return nil
+}
+
+// snapshot copies the diagonals -d..d of v.
+func snapshot(v []int, offset, d int) []int {
+	s := make([]int, 2*d+1)
+	copy(s, v[offset-d:offset+d+1])
+	return s
+}
+
+// backtrack walks the trace from (n, m) back to (0, 0), recovering the edits.
+func backtrack(trace [][]int, n, m int) []Edit {
+	var edits []Edit
+	x, y := n, m
+
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1]
+		at := func(k int) int { return prev[k+d-1] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, Edit{Op: Equal, A: x, B: y})
+		}
+		if x == prevX {
+			y--
+			edits = append(edits, Edit{Op: Insert, A: x, B: y})
+		} else {
+			x--
+			edits = append(edits, Edit{Op: Delete, A: x, B: y})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		edits = append(edits, Edit{Op: Equal, A: x, B: y})
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
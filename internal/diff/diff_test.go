@@ -0,0 +1,92 @@
+package diff
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// apply rebuilds both sides from an edit script.
+func apply(edits []Edit, a, b []string) (gotA, gotB []string) {
+	for _, e := range edits {
+		switch e.Op {
+		case Equal:
+			gotA = append(gotA, a[e.A])
+			gotB = append(gotB, b[e.B])
+		case Delete:
+			gotA = append(gotA, a[e.A])
+		case Insert:
+			gotB = append(gotB, b[e.B])
+		}
+	}
+	return gotA, gotB
+}
+
+func countChanges(edits []Edit) int {
+	n := 0
+	for _, e := range edits {
+		if e.Op != Equal {
+			n++
+		}
+	}
+	return n
+}
+
+func TestLines_Equal(t *testing.T) {
+	a := []string{"a", "b", "c"}
+	edits := Lines(a, a)
+	if len(edits) != 3 || countChanges(edits) != 0 {
+		t.Errorf("got %v, want 3 equal edits", edits)
+	}
+}
+
+func TestLines_Empty(t *testing.T) {
+	if edits := Lines(nil, nil); len(edits) != 0 {
+		t.Errorf("got %v, want no edits", edits)
+	}
+
+	edits := Lines(nil, []string{"a", "b"})
+	if len(edits) != 2 || edits[0].Op != Insert || edits[1].Op != Insert {
+		t.Errorf("got %v, want 2 inserts", edits)
+	}
+
+	edits = Lines([]string{"a", "b"}, nil)
+	if len(edits) != 2 || edits[0].Op != Delete || edits[1].Op != Delete {
+		t.Errorf("got %v, want 2 deletes", edits)
+	}
+}
+
+func TestLines_Minimal(t *testing.T) {
+	// Classic example from Myers' paper: the shortest edit script has 5 changes
+	a := strings.Split("ABCABBA", "")
+	b := strings.Split("CBABAC", "")
+	edits := Lines(a, b)
+
+	if n := countChanges(edits); n != 5 {
+		t.Errorf("got %d changes, want 5", n)
+	}
+	gotA, gotB := apply(edits, a, b)
+	if strings.Join(gotA, "") != "ABCABBA" || strings.Join(gotB, "") != "CBABAC" {
+		t.Errorf("edits do not rebuild inputs: %v %v", gotA, gotB)
+	}
+}
+
+func TestLines_RandomRebuild(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	words := []string{"a", "b", "c", "d"}
+	for i := 0; i < 200; i++ {
+		a := make([]string, rng.Intn(12))
+		for j := range a {
+			a[j] = words[rng.Intn(len(words))]
+		}
+		b := make([]string, rng.Intn(12))
+		for j := range b {
+			b[j] = words[rng.Intn(len(words))]
+		}
+
+		gotA, gotB := apply(Lines(a, b), a, b)
+		if strings.Join(gotA, ",") != strings.Join(a, ",") || strings.Join(gotB, ",") != strings.Join(b, ",") {
+			t.Fatalf("edits for %v -> %v rebuilt %v -> %v", a, b, gotA, gotB)
+		}
+	}
+}
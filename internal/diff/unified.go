@@ -0,0 +1,126 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// context is the number of unchanged lines shown around each change.
+const context = 3
+
+// Unified formats the difference between a and b as a unified diff, suitable
+// for `git apply` or `patch -p1`. Lines should include their line terminators;
+// a final line without one is marked "\ No newline at end of file".
+// Returns "" if a and b are equal.
+func Unified(oldName, newName string, a, b []string) string {
+	edits := Lines(a, b)
+
+	var sb strings.Builder
+	i := 0
+	for {
+		// Find the next change
+		for i < len(edits) && edits[i].Op == Equal {
+			i++
+		}
+		if i == len(edits) {
+			break
+		}
+
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+
+		// Extend the hunk over later changes whose context would overlap.
+		end := i
+		for {
+			for end < len(edits) && edits[end].Op != Equal {
+				end++
+			}
+			next := end
+			for next < len(edits) && edits[next].Op == Equal {
+				next++
+			}
+			if next < len(edits) && next-end <= 2*context {
+				end = next
+				continue
+			}
+			end += context
+			if end > len(edits) {
+				end = len(edits)
+			}
+			break
+		}
+
+		writeHunk(&sb, edits[start:end], a, b)
+		i = end
+	}
+	return sb.String()
+}
+
+// writeHunk writes one "@@ ... @@" hunk covering the given edits.
+func writeHunk(sb *strings.Builder, edits []Edit, a, b []string) {
+	aCount, bCount := 0, 0
+	for _, e := range edits {
+		if e.Op != Insert {
+			aCount++
+		}
+		if e.Op != Delete {
+			bCount++
+		}
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(edits[0].A, aCount), hunkRange(edits[0].B, bCount))
+
+	for _, e := range edits {
+		switch e.Op {
+		case Equal:
+			writeLine(sb, ' ', a[e.A])
+		case Delete:
+			writeLine(sb, '-', a[e.A])
+		case Insert:
+			writeLine(sb, '+', b[e.B])
+		}
+	}
+}
+
+// hunkRange formats a 0-indexed start and a line count as a hunk range.
+// An empty range names the line before it, as diff(1) does.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
+
+// writeLine writes a prefixed diff line, marking a missing final newline.
+func writeLine(sb *strings.Builder, prefix byte, line string) {
+	sb.WriteByte(prefix)
+	sb.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		sb.WriteString("\n\\ No newline at end of file\n")
+	}
+}
+
+// SplitLines splits text into lines, keeping each line's terminator.
+// The last line has no terminator if text does not end with "\n".
+func SplitLines(text string) []string {
+	var lines []string
+	for text != "" {
+		i := strings.IndexByte(text, '\n')
+		if i < 0 {
+			lines = append(lines, text)
+			break
+		}
+		lines = append(lines, text[:i+1])
+		text = text[i+1:]
+	}
+	return lines
+}
@@ -0,0 +1,89 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnified_NoChanges(t *testing.T) {
+	a := SplitLines("a\nb\n")
+	if got := Unified("a/x", "b/x", a, a); got != "" {
+		t.Errorf("got %q, want empty", got)
+	}
+}
+
+func TestUnified_SingleChange(t *testing.T) {
+	a := SplitLines("1\n2\n3\n4\n5\n6\n7\n8\n9\n")
+	b := SplitLines("1\n2\n3\n4\nfive\n6\n7\n8\n9\n")
+
+	want := "--- a/x\n+++ b/x\n" +
+		"@@ -2,7 +2,7 @@\n" +
+		" 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n"
+	if got := Unified("a/x", "b/x", a, b); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnified_SeparateHunks(t *testing.T) {
+	a := SplitLines("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n")
+	b := SplitLines("one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n")
+
+	want := "--- a/x\n+++ b/x\n" +
+		"@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n" +
+		"@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+ten\n"
+	if got := Unified("a/x", "b/x", a, b); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnified_MergedHunks(t *testing.T) {
+	// Changes 6 lines apart share context and become one hunk
+	a := SplitLines("1\n2\n3\n4\n5\n6\n7\n8\n")
+	b := SplitLines("one\n2\n3\n4\n5\n6\n7\neight\n")
+
+	want := "--- a/x\n+++ b/x\n" +
+		"@@ -1,8 +1,8 @@\n-1\n+one\n 2\n 3\n 4\n 5\n 6\n 7\n-8\n+eight\n"
+	if got := Unified("a/x", "b/x", a, b); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnified_EmptyRanges(t *testing.T) {
+	want := "--- a/x\n+++ b/x\n@@ -0,0 +1,2 @@\n+a\n+b\n"
+	if got := Unified("a/x", "b/x", nil, SplitLines("a\nb\n")); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	want = "--- a/x\n+++ b/x\n@@ -1 +0,0 @@\n-a\n"
+	if got := Unified("a/x", "b/x", SplitLines("a\n"), nil); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnified_NoNewlineAtEnd(t *testing.T) {
+	a := SplitLines("a\nb")
+	b := SplitLines("a\nb\n")
+
+	want := "--- a/x\n+++ b/x\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n"
+	if got := Unified("a/x", "b/x", a, b); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSplitLines(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"a", []string{"a"}},
+		{"a\n", []string{"a\n"}},
+		{"a\nb", []string{"a\n", "b"}},
+		{"a\r\n\nb\n", []string{"a\r\n", "\n", "b\n"}},
+	}
+	for _, tt := range tests {
+		if got := SplitLines(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitLines(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
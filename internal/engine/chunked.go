@@ -0,0 +1,148 @@
+package engine
+
+import (
+	"io"
+
+	"github.com/colinta/ged/internal/rule"
+)
+
+// ChunkSize is the number of lines ProcessChunked hands to a worker at once.
+const ChunkSize = 4096
+
+// Stateless reports whether every rule in the pipeline is stateless (see
+// rule.StatelessRule), so that lines can be processed in any order.
+// Pipelines containing order-dependent rules, such as control rules,
+// between ranges or line-number rules, are not.
+func (p *Pipeline) Stateless() bool {
+	for _, r := range p.rules {
+		if !rule.IsStateless(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// chunk is a run of consecutive input lines and, once processed, the
+// output of each: line i's output is out[ends[i-1]:ends[i]].
+type chunk struct {
+	lines   []string
+	out     []string
+	ends    []int
+	matched bool
+	err     error
+	done    chan struct{}
+}
+
+// ProcessChunked applies a stateless pipeline to the lines returned by
+// next, in chunks of ChunkSize lines spread over up to workers goroutines,
+// and calls emit with each line's output in input order. last is true for
+// the final line. next returns io.EOF at the end of input.
+//
+// next and emit are only called on the calling goroutine, and never at the
+// same time. Each chunk is processed with its own LineContext carrying
+// ctx.RecordSep and ctx.Context; ctx.Matched is set if any rule matched. The first error
+// stops processing: it is returned after the output of all earlier lines
+// has been emitted.
+//
+// Output is held back until a chunk is full, so this is meant for large
+// files rather than interactive streams.
+func (p *Pipeline) ProcessChunked(workers int, ctx *rule.LineContext, next func() (string, error), emit func(results []string, last bool) error) error {
+	sem := make(chan struct{}, workers)
+	var pending []*chunk
+	defer func() {
+		// Let running workers finish before returning, even on error.
+		for _, c := range pending {
+			<-c.done
+		}
+	}()
+
+	lineNum := 0
+	submit := func(lines []string) {
+		c := &chunk{lines: lines, done: make(chan struct{})}
+		first := lineNum
+		lineNum += len(lines)
+		pending = append(pending, c)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; close(c.done) }()
+			c.process(p, first, ctx)
+		}()
+	}
+	// flush emits chunks from the head of the queue, waiting for each to
+	// finish, until at most keep chunks are pending. last is set once the
+	// whole input has been submitted.
+	flush := func(keep int, last bool) error {
+		for len(pending) > keep {
+			c := pending[0]
+			<-c.done
+			pending = pending[1:]
+			if c.err != nil {
+				return c.err
+			}
+			if c.matched {
+				ctx.Matched = true
+			}
+			start := 0
+			for i, end := range c.ends {
+				if err := emit(c.out[start:end], last && len(pending) == 0 && i == len(c.ends)-1); err != nil {
+					return err
+				}
+				start = end
+			}
+		}
+		return nil
+	}
+
+	// One line is read ahead, so that a full chunk is only submitted once
+	// it is known whether its last line is the last of the input.
+	var lines []string
+	for {
+		line, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// Lines read before the error are still processed, and
+			// errors in them come first.
+			if len(lines) > 0 {
+				submit(lines)
+			}
+			if ferr := flush(0, false); ferr != nil {
+				return ferr
+			}
+			return err
+		}
+		if len(lines) == ChunkSize {
+			submit(lines)
+			lines = nil
+			// Bound memory: at most two chunks per worker are in flight.
+			if err := flush(2*workers, false); err != nil {
+				return err
+			}
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) > 0 {
+		submit(lines)
+	}
+	return flush(0, true)
+}
+
+// process runs the pipeline over the chunk's lines. first is the number
+// of lines before the chunk, so LineNum stays meaningful. The chunk's
+// context shares the document's record separator and cancellation.
+func (c *chunk) process(p *Pipeline, first int, doc *rule.LineContext) {
+	ctx := &rule.LineContext{RecordSep: doc.RecordSep, Context: doc.Context}
+	c.out = make([]string, 0, len(c.lines))
+	c.ends = make([]int, len(c.lines))
+	for i, line := range c.lines {
+		ctx.LineNum = first + i + 1
+		var err error
+		if c.out, err = p.Process(c.out, line, ctx); err != nil {
+			c.err = err
+			return
+		}
+		c.ends[i] = len(c.out)
+	}
+	c.matched = ctx.Matched
+}
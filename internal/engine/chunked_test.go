@@ -0,0 +1,150 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/colinta/ged/internal/rule"
+	"github.com/dlclark/regexp2"
+)
+
+func TestPipeline_Stateless(t *testing.T) {
+	sub, _ := rule.NewSubstitutionRule("a", "b")
+	print, _ := rule.NewPrintLineRule("b")
+	del, _ := rule.NewDeleteLineRule("c")
+	on, _ := rule.NewOnRule("x")
+
+	tests := []struct {
+		name  string
+		rules []rule.LineRule
+		want  bool
+	}{
+		{"line rules", []rule.LineRule{sub, print, del}, true},
+		{"control rule", []rule.LineRule{sub, on}, false},
+		{"line numbers", []rule.LineRule{rule.NewDeleteLineNumRule(rule.SingleLine(1))}, false},
+		{"conditional", []rule.LineRule{rule.NewConditionalLineRule(regexp2.MustCompile("x", 0), false, []rule.LineRule{sub})}, true},
+		{"conditional with control rule", []rule.LineRule{rule.NewConditionalLineRule(regexp2.MustCompile("x", 0), false, []rule.LineRule{on})}, false},
+		{"between", []rule.LineRule{rule.NewBetweenLineRule(regexp2.MustCompile("x", 0), regexp2.MustCompile("y", 0), false, []rule.LineRule{sub})}, false},
+	}
+	for _, tt := range tests {
+		if got := NewPipeline(tt.rules...).Stateless(); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// lineSource returns a next function over lines.
+func lineSource(lines []string) func() (string, error) {
+	i := 0
+	return func() (string, error) {
+		if i == len(lines) {
+			return "", io.EOF
+		}
+		i++
+		return lines[i-1], nil
+	}
+}
+
+func TestPipeline_ProcessChunked(t *testing.T) {
+	sub, _ := rule.NewSubstitutionRule("(\\d+)", "<$1>")
+	del, _ := rule.NewDeleteLineRule("7$")
+	p := NewPipeline(del, sub)
+
+	for _, n := range []int{0, 1, ChunkSize, 3*ChunkSize + 5} {
+		var input []string
+		var want []string
+		for i := 0; i < n; i++ {
+			line := fmt.Sprintf("line %d", i)
+			input = append(input, line)
+			results, _ := p.Process(nil, line, &rule.LineContext{})
+			want = append(want, results...)
+		}
+
+		var got []string
+		lasts := 0
+		ctx := &rule.LineContext{}
+		err := p.ProcessChunked(4, ctx, lineSource(input), func(results []string, last bool) error {
+			got = append(got, results...)
+			if last {
+				lasts++
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%d lines: unexpected error: %v", n, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%d lines: output differs from sequential processing", n)
+		}
+		if wantLasts := min(n, 1); lasts != wantLasts {
+			t.Errorf("%d lines: last reported %d times, want %d", n, lasts, wantLasts)
+		}
+		if ctx.Matched != (n > 0) {
+			t.Errorf("%d lines: got Matched=%v", n, ctx.Matched)
+		}
+	}
+}
+
+// failRule fails on one line.
+type failRule struct{ line string }
+
+func (r failRule) Apply(dst []string, line string, ctx *rule.LineContext) ([]string, error) {
+	if line == r.line {
+		return nil, errors.New("failed on " + line)
+	}
+	return append(dst, line), nil
+}
+
+func (r failRule) Stateless() bool { return true }
+
+func TestPipeline_ProcessChunkedError(t *testing.T) {
+	var input []string
+	for i := 0; i < 3*ChunkSize; i++ {
+		input = append(input, fmt.Sprint(i))
+	}
+	failAt := ChunkSize + 10
+	p := NewPipeline(failRule{line: fmt.Sprint(failAt)})
+
+	emitted := 0
+	err := p.ProcessChunked(4, &rule.LineContext{}, lineSource(input), func(results []string, last bool) error {
+		emitted++
+		return nil
+	})
+	// The error names the line, counted across chunks
+	want := fmt.Sprintf("line %d: engine.failRule: failed on %d", failAt+1, failAt)
+	if err == nil || err.Error() != want {
+		t.Fatalf("got %v, want %q", err, want)
+	}
+	// Everything in chunks before the failing one is emitted
+	if emitted != ChunkSize {
+		t.Errorf("emitted %d lines, want %d", emitted, ChunkSize)
+	}
+}
+
+func TestPipeline_ProcessChunkedReadError(t *testing.T) {
+	sub, _ := rule.NewSubstitutionRule("a", "b")
+	boom := errors.New("boom")
+	next := lineSource([]string{"a", "a"})
+	calls := 0
+	failing := func() (string, error) {
+		if calls++; calls > 2 {
+			return "", boom
+		}
+		return next()
+	}
+
+	var got []string
+	err := NewPipeline(sub).ProcessChunked(2, &rule.LineContext{}, failing, func(results []string, last bool) error {
+		got = append(got, results...)
+		return nil
+	})
+	if err != boom {
+		t.Errorf("got %v, want %v", err, boom)
+	}
+	if !reflect.DeepEqual(got, []string{"b", "b"}) {
+		t.Errorf("got %q, want the lines read before the error", got)
+	}
+}
@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/colinta/ged/internal/rule"
+)
+
+// Explain writes the program to w the way ged --explain shows it: whether
+// it streams or buffers its input, then the tree of rules it was compiled
+// to, with each pattern and how it is matched.
+func (p *Program) Explain(w io.Writer) error {
+	var sb strings.Builder
+	var rules []any
+	if len(p.docRules) == 0 {
+		sb.WriteString("mode: streaming (one line at a time)\n")
+		for _, lr := range p.lineRules {
+			rules = append(rules, lr)
+		}
+	} else {
+		// ApplyAllRule only groups line rules; the others are the reason
+		// for buffering.
+		var need []string
+		for _, dr := range p.docRules {
+			if _, ok := dr.(*rule.ApplyAllRule); !ok {
+				need = append(need, rule.Describe(dr))
+			}
+			rules = append(rules, dr)
+		}
+		fmt.Fprintf(&sb, "mode: buffered (needs the whole input: %s)\n", strings.Join(need, ", "))
+	}
+	sb.WriteByte('\n')
+	explainRules(&sb, rules, "")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// explainRules writes one line for each rule, followed by its patterns and
+// then its inner rules, indented under it.
+func explainRules(sb *strings.Builder, rules []any, indent string) {
+	for _, r := range rules {
+		sb.WriteString(indent)
+		sb.WriteString(strings.TrimPrefix(fmt.Sprintf("%T", r), "*rule."))
+		if s, ok := r.(fmt.Stringer); ok {
+			sb.WriteString(" " + s.String())
+		}
+		sb.WriteByte('\n')
+		if pr, ok := r.(rule.PatternRule); ok {
+			for _, m := range pr.Patterns() {
+				fmt.Fprintf(sb, "%s  pattern /%s/: %s\n", indent, m, rule.DescribePattern(m))
+			}
+		}
+		if g, ok := r.(rule.GroupRule); ok {
+			explainRules(sb, g.Inner(), indent+"  ")
+		}
+	}
+}
@@ -15,34 +15,17 @@ func NewPipeline(rules ...rule.LineRule) *Pipeline {
 	return &Pipeline{rules: rules}
 }
 
-// Process applies all rules to a line and returns the results.
+// Process applies all rules to a line and appends the results to dst.
 // ctx carries the 1-indexed line number and shared processing state.
-// If any rule returns an empty slice, processing stops and empty is returned.
-// Each output line from a rule feeds into the next rule.
-func (p *Pipeline) Process(line string, ctx *rule.LineContext) ([]string, error) {
-	// Start with the input line
-	lines := []string{line}
-
-	for _, r := range p.rules {
-		var nextLines []string
-
-		// Apply rule to each line from previous stage
-		for _, l := range lines {
-			result, err := r.Apply(l, ctx)
-			if err != nil {
-				return nil, err
-			}
-			// Collect all outputs
-			nextLines = append(nextLines, result...)
-		}
-
-		// If no output, stop processing
-		if len(nextLines) == 0 {
-			return []string{}, nil
-		}
-
-		lines = nextLines
+// If any rule produces no lines, processing stops and nothing is appended.
+// Each output line from a rule feeds into the next rule (see
+// rule.ApplyRules). Reusing dst from line to line, a line that stays one
+// line allocates nothing here.
+// Errors are rule.LineErrors naming the line and the rule that failed, or
+// reporting that ctx was cancelled before the line was processed.
+func (p *Pipeline) Process(dst []string, line string, ctx *rule.LineContext) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, &rule.LineError{Line: ctx.LineNum, Err: err}
 	}
-
-	return lines, nil
+	return rule.ApplyRules(dst, p.rules, line, ctx)
 }
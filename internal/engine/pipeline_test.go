@@ -10,7 +10,7 @@ func TestPipeline_SingleRule(t *testing.T) {
 	sub, _ := rule.NewSubstitutionRule("foo", "bar")
 	p := NewPipeline(sub)
 
-	result, err := p.Process("hello foo", &rule.LineContext{LineNum: 1})
+	result, err := p.Process(nil, "hello foo", &rule.LineContext{LineNum: 1})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -26,7 +26,7 @@ func TestPipeline_TwoRulesChain(t *testing.T) {
 	sub2, _ := rule.NewSubstitutionRule("b", "c")
 	p := NewPipeline(sub1, sub2)
 
-	result, err := p.Process("aaa", &rule.LineContext{LineNum: 1})
+	result, err := p.Process(nil, "aaa", &rule.LineContext{LineNum: 1})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -44,7 +44,7 @@ func TestPipeline_FilterThenSubstitute(t *testing.T) {
 	p := NewPipeline(print, sub)
 
 	// Line matches filter, gets transformed
-	result, err := p.Process("foo bar", &rule.LineContext{LineNum: 1})
+	result, err := p.Process(nil, "foo bar", &rule.LineContext{LineNum: 1})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -60,7 +60,7 @@ func TestPipeline_FilterStopsChain(t *testing.T) {
 	p := NewPipeline(print, sub)
 
 	// Line doesn't match filter - should produce no output
-	result, err := p.Process("bar only", &rule.LineContext{LineNum: 1})
+	result, err := p.Process(nil, "bar only", &rule.LineContext{LineNum: 1})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -76,7 +76,7 @@ func TestPipeline_SubstituteThenFilter(t *testing.T) {
 	p := NewPipeline(sub, print)
 
 	// "hello world" becomes "foo world", which matches filter
-	result, err := p.Process("hello world", &rule.LineContext{LineNum: 1})
+	result, err := p.Process(nil, "hello world", &rule.LineContext{LineNum: 1})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -85,7 +85,7 @@ func TestPipeline_SubstituteThenFilter(t *testing.T) {
 	}
 
 	// "goodbye world" stays "goodbye world", doesn't match filter
-	result, err = p.Process("goodbye world", &rule.LineContext{LineNum: 2})
+	result, err = p.Process(nil, "goodbye world", &rule.LineContext{LineNum: 2})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -97,7 +97,7 @@ func TestPipeline_SubstituteThenFilter(t *testing.T) {
 func TestPipeline_EmptyPipeline(t *testing.T) {
 	p := NewPipeline()
 
-	result, err := p.Process("hello", &rule.LineContext{LineNum: 1})
+	result, err := p.Process(nil, "hello", &rule.LineContext{LineNum: 1})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -115,14 +115,80 @@ func TestPipeline_DeleteRule(t *testing.T) {
 	p := NewPipeline(del, sub)
 
 	// Non-matching line passes through and gets substituted
-	result, _ := p.Process("public info", &rule.LineContext{LineNum: 1})
+	result, _ := p.Process(nil, "public info", &rule.LineContext{LineNum: 1})
 	if len(result) != 1 || result[0] != "PUBLIC info" {
 		t.Errorf("got %v, want [\"PUBLIC info\"]", result)
 	}
 
 	// Matching line is deleted
-	result, _ = p.Process("secret data", &rule.LineContext{LineNum: 2})
+	result, _ = p.Process(nil, "secret data", &rule.LineContext{LineNum: 2})
 	if len(result) != 0 {
 		t.Errorf("got %v, want []", result)
 	}
 }
+
+func TestPipeline_AppendsToDst(t *testing.T) {
+	sub, _ := rule.NewSubstitutionRule("-", "\n", rule.WithGlobal())
+	p := NewPipeline(sub)
+
+	result, err := p.Process([]string{"kept"}, "a-b", &rule.LineContext{LineNum: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result) != 3 || result[0] != "kept" || result[1] != "a" || result[2] != "b" {
+		t.Errorf("got %v, want [kept a b]", result)
+	}
+}
+
+// passThrough is a pipeline that leaves "INFO ok" as it is, through rules
+// of every kind that can stream.
+func passThrough(t testing.TB) *Pipeline {
+	t.Helper()
+	mustMatcher := func(pattern string) rule.Matcher {
+		m, err := rule.CompilePattern(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	keep, _ := rule.NewPrintLineRule("INFO")
+	sub, _ := rule.NewSubstitutionRule("DEBUG", "INFO", rule.WithGlobal())
+	del, _ := rule.NewDeleteLineRule("WARN")
+	on, _ := rule.NewOnRule("INFO")
+	cond := rule.NewConditionalLineRule(mustMatcher("ok"), false, []rule.LineRule{del, sub})
+	between := rule.NewBetweenLineRule(mustMatcher("start"), mustMatcher("end"), false, []rule.LineRule{del})
+	return NewPipeline(keep, sub, on, cond, between)
+}
+
+func TestPipeline_NoAllocations(t *testing.T) {
+	p := passThrough(t)
+	ctx := &rule.LineContext{}
+	var out []string
+	allocs := testing.AllocsPerRun(100, func() {
+		ctx.LineNum++
+		var err error
+		if out, err = p.Process(out[:0], "INFO ok", ctx); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("got %v allocations per line, want 0", allocs)
+	}
+	if len(out) != 1 || out[0] != "INFO ok" {
+		t.Errorf("got %v, want [INFO ok]", out)
+	}
+}
+
+func BenchmarkPipeline_Process(b *testing.B) {
+	p := passThrough(b)
+	ctx := &rule.LineContext{}
+	var out []string
+	b.ReportAllocs()
+	for b.Loop() {
+		ctx.LineNum++
+		var err error
+		if out, err = p.Process(out[:0], "INFO ok", ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/colinta/ged/internal/lineio"
+	"github.com/colinta/ged/internal/rule"
+)
+
+// Program is a compiled rule list, ready to be applied to any number of
+// documents. Rules keep their per-run state in the LineContext, so a
+// Program may be used by several goroutines at once as long as each
+// document gets its own context.
+type Program struct {
+	lineRules []rule.LineRule     // all rules, when there are no document rules
+	docRules  []rule.DocumentRule // document rules, with line rules wrapped in ApplyAllRule
+}
+
+// Compile builds a program from the rules returned by the parser, each a
+// rule.LineRule or a rule.DocumentRule.
+func Compile(allParsed []any) (*Program, error) {
+	// Build a list of DocumentRules.
+	// Consecutive LineRules are wrapped in an ApplyAllRule.
+	var docRules []rule.DocumentRule
+	var pendingLineRules []rule.LineRule
+
+	for _, parsed := range allParsed {
+		switch r := parsed.(type) {
+		case rule.LineRule:
+			pendingLineRules = append(pendingLineRules, r)
+		case rule.DocumentRule:
+			// Flush any pending line rules into an ApplyAllRule first
+			if len(pendingLineRules) > 0 {
+				docRules = append(docRules, rule.NewApplyAllRule(pendingLineRules))
+				pendingLineRules = nil
+			}
+			docRules = append(docRules, r)
+		default:
+			return nil, fmt.Errorf("unknown rule type from parser: %T", parsed)
+		}
+	}
+
+	if len(docRules) == 0 {
+		return &Program{lineRules: pendingLineRules}, nil
+	}
+
+	// Document rules exist — flush any trailing line rules.
+	if len(pendingLineRules) > 0 {
+		docRules = append(docRules, rule.NewApplyAllRule(pendingLineRules))
+	}
+	return &Program{docRules: docRules}, nil
+}
+
+// Pipeline returns the program's rules as a pipeline, or nil if the
+// program has document rules and so cannot process a line at a time.
+func (p *Program) Pipeline() *Pipeline {
+	if len(p.docRules) > 0 {
+		return nil
+	}
+	return NewPipeline(p.lineRules...)
+}
+
+// Records calls fn with each record of a document, in order. eol is the
+// terminator to write between a record's outputs and end the one to write
+// after the last; end is "" only for a final record without a terminator.
+// Records stops at the first error from fn and returns it.
+type Records func(fn func(line, eol, end string) error) error
+
+// ReaderRecords returns the records of the document read by lines.
+func ReaderRecords(lines *lineio.Reader) Records {
+	return func(fn func(line, eol, end string) error) error {
+		for {
+			line, err := lines.ReadLine()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := fn(line, lines.Terminator(), lines.End()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Process applies the program to one document and writes the result to w.
+// ctx must be fresh for each document; afterwards ctx.Matched reports
+// whether any rule matched. sep is the terminator written after output
+// records when the document has none of its own to copy.
+//
+// Without document rules the document is streamed, so a record's output is
+// written before the next record is read. Otherwise the whole document is
+// read first, and written with its first record's terminator.
+// Errors from the rules are wrapped; errors from records and from writing
+// to w are returned as they are.
+func (p *Program) Process(records Records, ctx *rule.LineContext, w io.Writer, sep string) error {
+	s := p.NewStream(ctx, w, sep)
+	if err := records(s.Add); err != nil {
+		return err
+	}
+	return s.Close()
+}
+
+// Stream applies a program to one document whose records are given one at
+// a time, for callers that cannot hand Process a Records function because
+// their input is pushed to them. See Process for the arguments.
+type Stream struct {
+	prog     *Program
+	pipeline *Pipeline // nil with document rules
+	ctx      *rule.LineContext
+	w        io.Writer
+	out      []string // a record's output, reused for the next
+
+	// With document rules: the records so far, and the terminators to
+	// write the document with.
+	lines       []string
+	eol, docEnd string
+}
+
+// NewStream starts applying the program to a document.
+func (p *Program) NewStream(ctx *rule.LineContext, w io.Writer, sep string) *Stream {
+	s := &Stream{prog: p, ctx: ctx, w: w, eol: sep, docEnd: sep}
+	// If there are no document rules, stream input line-by-line.
+	// This avoids buffering and works with infinite streams (e.g. tail -f).
+	if len(p.docRules) == 0 {
+		s.pipeline = NewPipeline(p.lineRules...)
+
+		// Call Setup on any rules that need it
+		for _, lr := range p.lineRules {
+			if setup, ok := lr.(rule.SetupRule); ok {
+				setup.Setup(ctx)
+			}
+		}
+	}
+	return s
+}
+
+// Add applies the program to the next record, with the terminators
+// described at Records. Without document rules, the record's output is
+// written before Add returns.
+func (s *Stream) Add(line, eol, end string) error {
+	if s.pipeline == nil {
+		// Document rules exist — buffer all input.
+		if len(s.lines) == 0 {
+			s.eol = eol
+		}
+		s.lines = append(s.lines, line)
+		s.docEnd = end
+		return nil
+	}
+
+	s.ctx.LineNum++
+	var err error
+	s.out, err = s.pipeline.Process(s.out[:0], line, s.ctx)
+	if err != nil {
+		return fmt.Errorf("error applying rules: %w", err)
+	}
+	if s.ctx.Printing == rule.PrintOff {
+		return nil
+	}
+	return lineio.WriteLines(s.w, s.out, eol, end)
+}
+
+// Close ends the document. With document rules, this is when they are
+// applied and the document is written.
+func (s *Stream) Close() error {
+	if s.pipeline != nil {
+		return nil
+	}
+	lines := s.lines
+	for _, dr := range s.prog.docRules {
+		if err := s.ctx.Err(); err != nil {
+			return err
+		}
+		var err error
+		lines, err = dr.ApplyDocument(lines, s.ctx)
+		if err != nil {
+			return fmt.Errorf("error applying rules: %w", err)
+		}
+	}
+	return lineio.WriteLines(s.w, lines, s.eol, s.docEnd)
+}
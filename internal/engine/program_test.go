@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/colinta/ged/internal/lineio"
+	"github.com/colinta/ged/internal/rule"
+)
+
+func mustCompile(t *testing.T, parsed ...any) *Program {
+	t.Helper()
+	prog, err := Compile(parsed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return prog
+}
+
+func runProgram(t *testing.T, prog *Program, input string) (string, bool) {
+	t.Helper()
+	var out bytes.Buffer
+	ctx := &rule.LineContext{}
+	records := ReaderRecords(lineio.NewReader(strings.NewReader(input)))
+	if err := prog.Process(records, ctx, &out, lineio.LF); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return out.String(), ctx.Matched
+}
+
+func TestProgram_LineRules(t *testing.T) {
+	sub, _ := rule.NewSubstitutionRule("a", "b")
+	prog := mustCompile(t, sub)
+	if prog.Pipeline() == nil {
+		t.Fatal("expected a pipeline for line rules")
+	}
+
+	got, matched := runProgram(t, prog, "a\r\nc")
+	if got != "b\r\nc" || !matched {
+		t.Errorf("got %q, %v; want %q, true", got, matched, "b\r\nc")
+	}
+}
+
+func TestProgram_DocumentRules(t *testing.T) {
+	sub, _ := rule.NewSubstitutionRule("a", "b")
+	prog := mustCompile(t, sub, rule.NewSortRule())
+	if prog.Pipeline() != nil {
+		t.Error("expected no pipeline with document rules")
+	}
+
+	got, _ := runProgram(t, prog, "c\na\n")
+	if got != "b\nc\n" {
+		t.Errorf("got %q, want %q", got, "b\nc\n")
+	}
+}
+
+func TestProgram_Cancelled(t *testing.T) {
+	sub, _ := rule.NewSubstitutionRule("a", "b")
+	for _, prog := range []*Program{mustCompile(t, sub), mustCompile(t, sub, rule.NewSortRule())} {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		lineCtx := &rule.LineContext{Context: ctx}
+		records := ReaderRecords(lineio.NewReader(strings.NewReader("a\na\n")))
+		err := prog.Process(records, lineCtx, io.Discard, lineio.LF)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	}
+}
+
+func TestProgram_Explain(t *testing.T) {
+	sub, _ := rule.NewSubstitutionRule("a", "b", rule.WithGlobal())
+	del, _ := rule.NewDeleteLineRule(`^\s*$`)
+	cond, _ := rule.CompilePattern("x")
+	head, _ := rule.ParseLineRange("1-5")
+	second, _ := rule.ParseLineRange("2")
+	ends, _ := rule.ParseLineRange("-1,4-")
+	tests := []struct {
+		name string
+		prog *Program
+		want string
+	}{
+		{"streaming", mustCompile(t, sub, rule.NewConditionalLineRule(cond, false, []rule.LineRule{del})), `mode: streaming (one line at a time)
+
+SubstitutionRule s/a/b/g
+  pattern /a/: literal
+ConditionalLineRule if/x/
+  pattern /x/: literal
+  DeleteLineRule d/^\s*$/
+    pattern /^\s*$/: ecmascript
+`},
+		{"buffered", mustCompile(t, sub, rule.NewConditionalDocRule(cond, true, []rule.DocumentRule{rule.NewSortRule()})), `mode: buffered (needs the whole input: !if/x/)
+
+ApplyAllRule
+  SubstitutionRule s/a/b/g
+    pattern /a/: literal
+ConditionalDocRule !if/x/
+  pattern /x/: literal
+  SortRule
+`},
+		{"line numbers", mustCompile(t, rule.NewPrintLineNumRule(head), rule.NewSubLineNumRule(second, "X"), rule.NewDeleteLineNumRule(ends), rule.NewJoinRule(",")), `mode: buffered (needs the whole input: join/,/)
+
+ApplyAllRule
+  PrintLineNumRule p:1-5
+  SubLineNumRule s:2:X
+  DeleteLineNumRule d:-1,4-
+JoinRule join/,/
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := tt.prog.Explain(&out); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestCompile_UnknownRule(t *testing.T) {
+	if _, err := Compile([]any{"s/a/b/"}); err == nil {
+		t.Error("expected error for a value that is not a rule")
+	}
+}
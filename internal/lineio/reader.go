@@ -0,0 +1,255 @@
+// Package lineio reads and writes the lines that ged's rules operate on.
+package lineio
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Line terminators recognized by Reader.
+const (
+	LF   = "\n"
+	CRLF = "\r\n"
+)
+
+// ParagraphSep is the record separator rules see in paragraph mode:
+// paragraphs are joined by a single blank line.
+const ParagraphSep = "\n\n"
+
+// end records what followed the last record read.
+type end int
+
+const (
+	endNone   end = iota // nothing: the input had no final newline
+	endRecord            // a full record terminator
+	endLine              // a line terminator ending the input (paragraph mode)
+)
+
+// Reader reads lines from an io.Reader. Unlike bufio.Scanner, it has no
+// maximum line length: a line is buffered in full however long it is.
+// Lines are read one at a time, so input still streams.
+//
+// A Reader created with NewRecordReader reads records ending in an arbitrary
+// separator instead, and one created with NewParagraphReader reads
+// paragraphs; everything said here about lines applies to records.
+//
+// Reader records how the input's lines were terminated, so that output
+// can be written back the same way: see Terminator and End.
+type Reader struct {
+	r         *bufio.Reader
+	sep       string // record separator; "" means LF or CRLF lines
+	paragraph bool   // records are runs of non-blank lines
+	eol       string // terminator of the first terminated line
+	lastEOL   string // terminator of the last line read, in line mode
+	force     string // line terminator set by SetTerminator
+	end       end
+
+	pending    string // paragraph mode: first line of the next paragraph
+	pendingEOL string
+	hasPending bool
+}
+
+// NewReader creates a Reader reading LF or CRLF terminated lines from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// NewRecordReader creates a Reader reading records from r that are
+// terminated by sep, such as "\x00" or "\n---\n". sep must not be empty.
+func NewRecordReader(r io.Reader, sep string) *Reader {
+	return &Reader{r: bufio.NewReader(r), sep: sep, eol: sep}
+}
+
+// NewParagraphReader creates a Reader whose records are paragraphs: runs of
+// non-blank lines, separated by one or more blank lines, as with awk's
+// RS="". A line containing only whitespace counts as blank. The lines of a
+// paragraph are joined with their own terminators, so a rule sees the
+// newlines inside it; blank lines before the first paragraph and after
+// the last one are dropped.
+func NewParagraphReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r), paragraph: true}
+}
+
+// SetTerminator makes the Reader report eol as the input's line or record
+// terminator, so that output can be written in a different convention.
+// In paragraph mode it also replaces the terminators inside paragraphs.
+func (r *Reader) SetTerminator(eol string) {
+	r.force = eol
+}
+
+// ReadLine returns the next line without its "\n" or "\r\n" terminator,
+// so rules never see a stray "\r". A final line without a terminator is
+// returned as a normal line, and End reports "" for it.
+// At the end of input ReadLine returns "", io.EOF.
+func (r *Reader) ReadLine() (string, error) {
+	switch {
+	case r.sep != "":
+		return r.readRecord()
+	case r.paragraph:
+		return r.readParagraph()
+	}
+
+	line, eol, err := r.readLine()
+	if err != nil {
+		return "", err
+	}
+	r.end = endNone
+	if eol != "" {
+		r.end = endRecord
+	}
+	r.lastEOL = eol
+	return line, nil
+}
+
+// readLine reads one LF or CRLF terminated line, returning the line and
+// its terminator separately.
+func (r *Reader) readLine() (line, eol string, err error) {
+	line, err = r.r.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", "", err
+	}
+
+	if strings.HasSuffix(line, CRLF) {
+		eol = CRLF
+	} else if strings.HasSuffix(line, LF) {
+		eol = LF
+	}
+	if r.eol == "" {
+		r.eol = eol
+	}
+	return line[:len(line)-len(eol)], eol, nil
+}
+
+// readRecord reads up to and including the next separator, which may be
+// several bytes long, and returns the record without it.
+func (r *Reader) readRecord() (string, error) {
+	last := r.sep[len(r.sep)-1]
+	var sb strings.Builder
+	for {
+		chunk, err := r.r.ReadString(last)
+		sb.WriteString(chunk)
+		if err != nil {
+			if err != io.EOF || sb.Len() == 0 {
+				return "", err
+			}
+			r.end = endNone
+			return sb.String(), nil
+		}
+		if record := sb.String(); strings.HasSuffix(record, r.sep) {
+			r.end = endRecord
+			return record[:len(record)-len(r.sep)], nil
+		}
+	}
+}
+
+// readParagraph reads the next run of non-blank lines. To tell whether the
+// paragraph is the last one, it reads ahead past the blank lines that
+// follow it and keeps the first line of the next paragraph for later.
+func (r *Reader) readParagraph() (string, error) {
+	var sb strings.Builder
+	lastEOL := ""
+	for {
+		var line, eol string
+		var err error
+		if r.hasPending {
+			line, eol, r.hasPending = r.pending, r.pendingEOL, false
+		} else {
+			line, eol, err = r.readLine()
+		}
+		if err == io.EOF && sb.Len() > 0 {
+			r.end = endNone
+			if lastEOL != "" {
+				r.end = endLine
+			}
+			return sb.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if isBlank(line) {
+			if sb.Len() == 0 {
+				continue
+			}
+			return sb.String(), r.skipBlankLines()
+		}
+		if sb.Len() > 0 {
+			if r.force != "" {
+				lastEOL = r.force
+			}
+			sb.WriteString(lastEOL)
+		}
+		sb.WriteString(line)
+		lastEOL = eol
+	}
+}
+
+// skipBlankLines reads past the blank lines after a paragraph. If another
+// paragraph follows, its first line is kept for the next readParagraph.
+func (r *Reader) skipBlankLines() error {
+	for {
+		line, eol, err := r.readLine()
+		if err == io.EOF {
+			r.end = endLine
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !isBlank(line) {
+			r.pending, r.pendingEOL, r.hasPending = line, eol, true
+			r.end = endRecord
+			return nil
+		}
+	}
+}
+
+// isBlank reports whether a line separates paragraphs.
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// Terminator returns the input's line terminator: the terminator of the
+// first terminated line, either LF or CRLF. It is LF until a terminated
+// line has been read. For a record reader it is always the separator, and
+// for a paragraph reader it is a blank line: the line terminator, twice.
+// SetTerminator overrides the line or record terminator.
+func (r *Reader) Terminator() string {
+	eol := r.lineTerminator()
+	if r.paragraph {
+		return eol + eol
+	}
+	return eol
+}
+
+// lineTerminator returns the terminator of a single line or record.
+func (r *Reader) lineTerminator() string {
+	switch {
+	case r.force != "":
+		return r.force
+	case r.eol == "":
+		return LF
+	default:
+		return r.eol
+	}
+}
+
+// End returns what to write after the output of the last record returned
+// by ReadLine: normally the line's own terminator, so that a file mixing LF
+// and CRLF is written back as it was, but "" for a final line without a
+// trailing newline. For records it is Terminator, and after the last
+// paragraph a single line terminator. SetTerminator overrides it.
+func (r *Reader) End() string {
+	switch r.end {
+	case endRecord:
+		if r.lastEOL != "" && r.force == "" {
+			return r.lastEOL
+		}
+		return r.Terminator()
+	case endLine:
+		return r.lineTerminator()
+	default:
+		return ""
+	}
+}
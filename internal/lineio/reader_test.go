@@ -0,0 +1,225 @@
+package lineio
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// readAll reads every line from r.
+func readAll(t *testing.T, r *Reader) []string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := r.ReadLine()
+		if err == io.EOF {
+			return lines
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestReader_Lines(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"a", []string{"a"}},
+		{"a\n", []string{"a"}},
+		{"a\nb", []string{"a", "b"}},
+		{"a\n\nb\n", []string{"a", "", "b"}},
+		{"a\r\nb\r\n", []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		got := readAll(t, NewReader(strings.NewReader(tt.in)))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestReader_LongLine(t *testing.T) {
+	// Longer than bufio.Scanner's 64 KiB default token limit
+	long := strings.Repeat("x", 1<<20)
+	got := readAll(t, NewReader(strings.NewReader("a\n"+long+"\nb")))
+
+	if len(got) != 3 || got[0] != "a" || got[1] != long || got[2] != "b" {
+		t.Errorf("long line not read intact: got %d lines", len(got))
+	}
+}
+
+func TestReader_SmallReads(t *testing.T) {
+	got := readAll(t, NewReader(iotest.OneByteReader(strings.NewReader("ab\ncd\n"))))
+	if !reflect.DeepEqual(got, []string{"ab", "cd"}) {
+		t.Errorf("got %q, want [ab cd]", got)
+	}
+}
+
+func TestReader_Error(t *testing.T) {
+	boom := errors.New("boom")
+	r := NewReader(io.MultiReader(strings.NewReader("a\n"), iotest.ErrReader(boom)))
+
+	if line, err := r.ReadLine(); err != nil || line != "a" {
+		t.Fatalf("got %q, %v; want \"a\", nil", line, err)
+	}
+	if _, err := r.ReadLine(); err != boom {
+		t.Errorf("got %v, want %v", err, boom)
+	}
+}
+
+func TestReader_Terminator(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", LF},
+		{"a", LF},
+		{"a\nb\r\n", LF},
+		{"a\r\nb\n", CRLF},
+		{"a\r\n", CRLF},
+	}
+	for _, tt := range tests {
+		r := NewReader(strings.NewReader(tt.in))
+		readAll(t, r)
+		if got := r.Terminator(); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestReader_End(t *testing.T) {
+	r := NewReader(strings.NewReader("a\r\nb"))
+
+	if _, err := r.ReadLine(); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.End(); got != CRLF {
+		t.Errorf("first line: got End %q, want %q", got, CRLF)
+	}
+	if _, err := r.ReadLine(); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.End(); got != "" {
+		t.Errorf("final line without newline: got End %q, want \"\"", got)
+	}
+}
+
+func TestReader_EndMixed(t *testing.T) {
+	r := NewReader(strings.NewReader("a\r\nb\nc\r\n"))
+	for _, want := range []string{CRLF, LF, CRLF} {
+		if _, err := r.ReadLine(); err != nil {
+			t.Fatal(err)
+		}
+		if got := r.End(); got != want {
+			t.Errorf("got End %q, want %q", got, want)
+		}
+	}
+	if r.Terminator() != CRLF {
+		t.Errorf("got Terminator %q, want the first line's, CRLF", r.Terminator())
+	}
+}
+
+func TestReader_SetTerminator(t *testing.T) {
+	r := NewReader(strings.NewReader("a\nb\n"))
+	r.SetTerminator(CRLF)
+	readAll(t, r)
+	if r.Terminator() != CRLF || r.End() != CRLF {
+		t.Errorf("got Terminator %q, End %q; want CRLF for both", r.Terminator(), r.End())
+	}
+}
+
+func TestReader_LoneCarriageReturnKept(t *testing.T) {
+	// Only "\r\n" is a terminator; a "\r" elsewhere is content
+	got := readAll(t, NewReader(strings.NewReader("a\rb\nc\r")))
+	if !reflect.DeepEqual(got, []string{"a\rb", "c\r"}) {
+		t.Errorf("got %q, want [a\\rb c\\r]", got)
+	}
+}
+
+func TestRecordReader(t *testing.T) {
+	tests := []struct {
+		in   string
+		sep  string
+		want []string
+	}{
+		{"", "\x00", nil},
+		{"a\x00b\x00", "\x00", []string{"a", "b"}},
+		{"a\nb\x00c", "\x00", []string{"a\nb", "c"}},
+		{"a\n---\nb\n---\n", "\n---\n", []string{"a", "b"}},
+		// A partial separator is content
+		{"a--b\n---\nc", "\n---\n", []string{"a--b", "c"}},
+		{"x\r\ny", "\n", []string{"x\r", "y"}},
+	}
+	for _, tt := range tests {
+		got := readAll(t, NewRecordReader(strings.NewReader(tt.in), tt.sep))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q split on %q: got %q, want %q", tt.in, tt.sep, got, tt.want)
+		}
+	}
+}
+
+func TestRecordReader_End(t *testing.T) {
+	r := NewRecordReader(strings.NewReader("a\x00b"), "\x00")
+	if r.Terminator() != "\x00" {
+		t.Errorf("Terminator: got %q, want %q", r.Terminator(), "\x00")
+	}
+
+	if _, err := r.ReadLine(); err != nil || r.End() != "\x00" {
+		t.Errorf("first record: err=%v end=%q, want nil and NUL", err, r.End())
+	}
+	if _, err := r.ReadLine(); err != nil || r.End() != "" {
+		t.Errorf("final record: err=%v end=%q, want nil and \"\"", err, r.End())
+	}
+}
+
+func TestParagraphReader(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+		end  string
+	}{
+		{"", nil, ""},
+		{"\n\n", nil, ""},
+		{"a\nb\n\nc\n", []string{"a\nb", "c"}, LF},
+		{"a\nb\n\nc", []string{"a\nb", "c"}, ""},
+		// Leading, repeated and trailing blank lines are dropped
+		{"\n\na\n\n\n\nb\n\n\n", []string{"a", "b"}, LF},
+		// Whitespace-only lines are blank
+		{"a\n \t\nb\n", []string{"a", "b"}, LF},
+		{"a\r\nb\r\n\r\nc\r\n", []string{"a\r\nb", "c"}, CRLF},
+	}
+	for _, tt := range tests {
+		r := NewParagraphReader(strings.NewReader(tt.in))
+		got := readAll(t, r)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %q, want %q", tt.in, got, tt.want)
+		}
+		if r.End() != tt.end {
+			t.Errorf("%q: got End %q, want %q", tt.in, r.End(), tt.end)
+		}
+	}
+}
+
+func TestParagraphReader_Terminator(t *testing.T) {
+	r := NewParagraphReader(strings.NewReader("a\n\nb\n"))
+	if _, err := r.ReadLine(); err != nil {
+		t.Fatal(err)
+	}
+	if r.Terminator() != "\n\n" || r.End() != "\n\n" {
+		t.Errorf("first paragraph: got Terminator %q, End %q; want a blank line", r.Terminator(), r.End())
+	}
+
+	r = NewParagraphReader(strings.NewReader("a\nb\n"))
+	r.SetTerminator(CRLF)
+	got := readAll(t, r)
+	if !reflect.DeepEqual(got, []string{"a\r\nb"}) || r.Terminator() != "\r\n\r\n" {
+		t.Errorf("forced CRLF: got %q, Terminator %q", got, r.Terminator())
+	}
+}
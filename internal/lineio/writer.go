@@ -0,0 +1,22 @@
+package lineio
+
+import "io"
+
+// WriteLines writes lines to w, each followed by eol except the last, which
+// is followed by end. Passing "" for end preserves an input's missing final
+// newline; see Reader.End.
+func WriteLines(w io.Writer, lines []string, eol, end string) error {
+	for i, line := range lines {
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+		term := eol
+		if i == len(lines)-1 {
+			term = end
+		}
+		if _, err := io.WriteString(w, term); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package lineio
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteLines(t *testing.T) {
+	tests := []struct {
+		lines []string
+		eol   string
+		end   string
+		want  string
+	}{
+		{nil, LF, LF, ""},
+		{nil, LF, "", ""},
+		{[]string{"a", "b"}, LF, LF, "a\nb\n"},
+		{[]string{"a", "b"}, LF, "", "a\nb"},
+		{[]string{"a", "b"}, CRLF, CRLF, "a\r\nb\r\n"},
+		{[]string{"a"}, CRLF, "", "a"},
+		{[]string{"a\nb", "c"}, "\n\n", LF, "a\nb\n\nc\n"},
+	}
+	for _, tt := range tests {
+		var sb strings.Builder
+		if err := WriteLines(&sb, tt.lines, tt.eol, tt.end); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if sb.String() != tt.want {
+			t.Errorf("WriteLines(%q, %q, %q) = %q, want %q", tt.lines, tt.eol, tt.end, sb.String(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Error is a parse error at a known place in a script. For a script file,
+// Name, Line and Col give the position; for rules given as command-line
+// arguments, Arg is the 1-indexed argument and Col the column within it.
+// Line, Col and Arg are 1-indexed, with Col counting characters.
+type Error struct {
+	Name string // script file name, if any
+	Line int
+	Arg  int
+	Col  int
+	Err  error
+
+	// Offset is the byte offset of the error in the script, or in the
+	// argument, and Text the line of it the error is on; Caret shows both.
+	Offset int
+	Text   string
+	// Rule is the text of the rule the error is in, when the rule as a
+	// whole is at fault, such as one whose pattern does not compile. For
+	// a rule from a macro it is the rule after expansion.
+	Rule string
+
+	arg     int // the 0-indexed argument, until Arg is set
+	textOff int // the byte offset of the error in Text
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Line > 0 && e.Name != "":
+		return fmt.Sprintf("%s:%d:%d: %v", e.Name, e.Line, e.Col, e.Err)
+	case e.Line > 0:
+		return fmt.Sprintf("%d:%d: %v", e.Line, e.Col, e.Err)
+	case e.Arg > 0:
+		return fmt.Sprintf("argument %d, column %d: %v", e.Arg, e.Col, e.Err)
+	default:
+		return e.Err.Error()
+	}
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Caret returns the line the error is on, indented, and under it a caret
+// pointing at the error:
+//
+//	s/a/b/; revers
+//	        ^
+//
+// It returns "" if the line is not known or is blank.
+func (e *Error) Caret() string {
+	if strings.TrimSpace(e.Text) == "" {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("  ")
+	sb.WriteString(e.Text)
+	sb.WriteString("\n  ")
+	// Tabs are kept so the caret lines up however wide they are shown.
+	for _, r := range e.Text[:min(e.textOff, len(e.Text))] {
+		if r == '\t' {
+			sb.WriteByte('\t')
+		} else {
+			sb.WriteByte(' ')
+		}
+	}
+	sb.WriteByte('^')
+	return sb.String()
+}
+
+// locate sets the error's line, column and text from its offset in src.
+func (e *Error) locate(name, src string) {
+	e.Name = name
+	e.Line, e.Col = 1, 1
+	for _, r := range src[:min(e.Offset, len(src))] {
+		if r == '\n' {
+			e.Line++
+			e.Col = 1
+		} else {
+			e.Col++
+		}
+	}
+	e.setText(src)
+}
+
+// locateArg sets the error's argument, column and text from its offset in
+// args.
+func (e *Error) locateArg(args []string) {
+	if e.arg >= len(args) {
+		return
+	}
+	arg := args[e.arg]
+	e.Arg = e.arg + 1
+	e.Col = utf8.RuneCountInString(arg[:min(e.Offset, len(arg))]) + 1
+	e.setText(arg)
+}
+
+// setText sets Text to the line of src that holds the error.
+func (e *Error) setText(src string) {
+	at := min(e.Offset, len(src))
+	start := strings.LastIndexByte(src[:at], '\n') + 1
+	end := len(src)
+	if i := strings.IndexByte(src[at:], '\n'); i >= 0 {
+		end = at + i
+	}
+	e.Text = strings.TrimSuffix(src[start:end], "\r")
+	e.textOff = at - start
+}
@@ -0,0 +1,367 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// tokenKind is the kind of a script token.
+type tokenKind int
+
+const (
+	tokenRule  tokenKind = iota // a single rule, such as s/a/b/g or sort
+	tokenOpen                   // "{"
+	tokenClose                  // "}"
+)
+
+// token is one rule or brace from a script, with its offset in the source.
+// For rules given as command-line arguments, arg is the index of the
+// argument and offset is relative to it. Tokens from a macro's body are
+// placed where the macro was used, and macro names it.
+type token struct {
+	kind   tokenKind
+	text   string
+	arg    int
+	offset int
+	macro  string
+}
+
+// commandSpec describes how many delimited parts a command takes.
+type commandSpec struct {
+	parts    int  // number of delimited parts
+	flags    bool // flag letters may follow the last delimiter
+	optional bool // the delimited parts may be left out entirely
+	lines    bool // a ':' delimiter introduces a line range
+}
+
+// commands lists the rule commands the lexer recognizes. The single-letter
+// commands s, p and d take line ranges instead when the delimiter is ':'.
+var commands = map[string]commandSpec{
+	"s":        {parts: 2, flags: true, lines: true},
+	"p":        {parts: 1, flags: true, lines: true},
+	"d":        {parts: 1, flags: true, lines: true},
+	"sort":     {},
+	"reverse":  {},
+	"join":     {parts: 1, optional: true},
+	"if":       {parts: 1, flags: true},
+	"!if":      {parts: 1, flags: true},
+	"between":  {parts: 2, flags: true},
+	"!between": {parts: 2, flags: true},
+	"on":       {parts: 1, flags: true},
+	"off":      {parts: 1, flags: true},
+	"after":    {parts: 1, flags: true},
+	"toggle":   {parts: 1, flags: true},
+}
+
+// lineRangeChars are the characters of a line range such as "1,3,5-7".
+const lineRangeChars = "0123456789,-"
+
+// lexer splits a script into tokens. Rules are found by their syntax rather
+// than by whitespace: a rule is a command name, a delimiter, and as many
+// delimited parts as the command takes, so delimited parts may contain
+// spaces, braces, semicolons and '#'. Whitespace and ';' separate rules,
+// and '#' at the start of a token begins a comment that runs to the end of
+// the line.
+//
+// If a rule's last part has no closing delimiter it runs to the end of the
+// line, so "s/a/b" works as it does on the command line; close it to put
+// anything else after it on the same line.
+//
+// A rule may also be written in single or double quotes, as it would be on
+// a shell command line. Single quotes are literal; inside double quotes \"
+// and \\ are escapes.
+type lexer struct {
+	src    string
+	arg    int // index of the argument being lexed
+	pos    int
+	tokens []token
+	macros map[string]*Macro // macros that may be used as commands
+
+	// In an argument, a newline is an ordinary character inside a rule,
+	// since the shell has already done the splitting.
+	newlineInRule bool
+}
+
+// lex splits src into tokens.
+func lex(src string, macros map[string]*Macro) ([]token, error) {
+	l := &lexer{src: src, macros: macros}
+	return l.run()
+}
+
+// lexArgs splits each command-line argument into tokens, so one argument
+// may hold a whole script and "{" and "}" need not be separate arguments.
+func lexArgs(args []string, macros map[string]*Macro) ([]token, error) {
+	var toks []token
+	for i, arg := range args {
+		l := &lexer{src: arg, arg: i, macros: macros, newlineInRule: true}
+		argToks, err := l.run()
+		if err != nil {
+			return nil, err
+		}
+		toks = append(toks, argToks...)
+	}
+	return toks, nil
+}
+
+// run lexes the whole source.
+func (l *lexer) run() ([]token, error) {
+	for {
+		l.skipSpace()
+		if l.pos >= len(l.src) {
+			return l.tokens, nil
+		}
+
+		switch ch := l.src[l.pos]; ch {
+		case '{':
+			l.emit(tokenOpen, "{", l.pos)
+			l.pos++
+		case '}':
+			l.emit(tokenClose, "}", l.pos)
+			l.pos++
+		case '\'', '"':
+			if err := l.lexQuoted(ch); err != nil {
+				return nil, err
+			}
+		default:
+			if err := l.lexRule(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (l *lexer) emit(kind tokenKind, text string, offset int) {
+	l.tokens = append(l.tokens, token{kind: kind, text: text, arg: l.arg, offset: offset})
+}
+
+// skipSpace skips whitespace, ';' separators and comments.
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.src) {
+		switch l.src[l.pos] {
+		case ' ', '\t', '\r', '\n', ';':
+			l.pos++
+		case '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// errorf returns an Error at offset in the source.
+func (l *lexer) errorf(offset int, format string, args ...any) error {
+	return &Error{Err: fmt.Errorf(format, args...), arg: l.arg, Offset: offset}
+}
+
+// lexQuoted reads a rule written in shell-style quotes.
+func (l *lexer) lexQuoted(quote byte) error {
+	start := l.pos
+	var sb strings.Builder
+	for l.pos++; l.pos < len(l.src); l.pos++ {
+		ch := l.src[l.pos]
+		if ch == quote {
+			l.pos++
+			l.emit(tokenRule, sb.String(), start)
+			return nil
+		}
+		if quote == '"' && ch == '\\' && l.pos+1 < len(l.src) && (l.src[l.pos+1] == '"' || l.src[l.pos+1] == '\\') {
+			l.pos++
+			ch = l.src[l.pos]
+		}
+		sb.WriteByte(ch)
+	}
+	return l.errorf(start, "unterminated %c quote", quote)
+}
+
+// lexRule reads one unquoted rule.
+func (l *lexer) lexRule() error {
+	start := l.pos
+	end := start
+	if end < len(l.src) && l.src[end] == '!' {
+		end++
+	}
+	for end < len(l.src) && isLetter(l.src[end]) {
+		end++
+	}
+	name := l.src[start:end]
+
+	spec, ok := commands[name]
+	if m, isMacro := l.macros[name]; !ok && isMacro {
+		spec, ok = commandSpec{parts: len(m.Params)}, true
+	}
+	if !ok {
+		if name == "" || name == "!" {
+			r, _ := utf8.DecodeRuneInString(l.src[start:])
+			return l.errorf(start, "unexpected %q", r)
+		}
+		if guess := l.suggest(name); guess != "" {
+			return l.errorf(start, "unknown command %q; did you mean %q?", name, guess)
+		}
+		return l.errorf(start, "unknown command %q", name)
+	}
+	l.pos = end
+
+	if spec.parts > 0 && !(spec.optional && l.atBoundary()) {
+		if err := l.lexParts(name, spec); err != nil {
+			return err
+		}
+	}
+	if !l.atBoundary() {
+		r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+		return l.errorf(l.pos, "unexpected %q after %s rule", r, name)
+	}
+	l.emit(tokenRule, l.src[start:l.pos], start)
+	return nil
+}
+
+// suggest returns the command or macro whose name is closest to name, if
+// one is close enough to be a likely typo, or "". Names shorter than three
+// letters are too short to guess from.
+func (l *lexer) suggest(name string) string {
+	if len(name) < 3 {
+		return ""
+	}
+	maxDist := 1
+	if len(name) > 4 {
+		maxDist = 2
+	}
+	best, bestDist := "", maxDist+1
+	consider := func(candidate string) {
+		d := editDistance(name, candidate)
+		// Ties go to the name that sorts first, so the guess is stable.
+		if d < bestDist || d == bestDist && candidate < best {
+			best, bestDist = candidate, d
+		}
+	}
+	for candidate := range commands {
+		consider(candidate)
+	}
+	for candidate := range l.macros {
+		consider(candidate)
+	}
+	return best
+}
+
+// editDistance returns the number of single-character insertions,
+// deletions, substitutions and adjacent transpositions that turn a into b.
+func editDistance(a, b string) int {
+	// prev2, prev and cur are rows of the usual dynamic-programming table.
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
+
+// lexParts reads a rule's delimiter, delimited parts and flags.
+func (l *lexer) lexParts(name string, spec commandSpec) error {
+	if l.pos >= len(l.src) || l.src[l.pos] == '\n' {
+		return l.errorf(l.pos, "%s requires a delimiter, as in %s/.../", name, name)
+	}
+	delim, size := utf8.DecodeRuneInString(l.src[l.pos:])
+	if delim >= utf8.RuneSelf || isLetter(byte(delim)) || isDigit(byte(delim)) || isSpace(byte(delim)) {
+		return l.errorf(l.pos, "invalid delimiter %q after %s: delimiters must be punctuation", delim, name)
+	}
+	l.pos += size
+
+	parts := spec.parts
+	if delim == ':' && spec.lines {
+		// Line numbers: the range ends at the first character that can't
+		// be part of one. s:RANGE:replacement takes one more part.
+		for l.pos < len(l.src) && strings.IndexByte(lineRangeChars, l.src[l.pos]) >= 0 {
+			l.pos++
+		}
+		if name != "s" {
+			if l.pos < len(l.src) && l.src[l.pos] == ':' {
+				l.pos++
+			}
+			return nil
+		}
+		if l.pos >= len(l.src) || l.src[l.pos] != ':' {
+			return l.errorf(l.pos, "expected ':' after the line range in s:")
+		}
+		l.pos++
+		parts = 1
+	}
+
+	for i := 0; i < parts; i++ {
+		closed := l.lexPart(byte(delim))
+		if !closed {
+			if i < parts-1 {
+				return l.errorf(l.pos, "%s rule needs %d parts: missing %q", name, spec.parts, delim)
+			}
+			return nil
+		}
+	}
+	if spec.flags {
+		for l.pos < len(l.src) && isLetter(l.src[l.pos]) {
+			l.pos++
+		}
+	}
+	return nil
+}
+
+// lexPart reads up to and including the next unescaped delimiter, and
+// reports whether one was found before the end of the line.
+func (l *lexer) lexPart(delim byte) bool {
+	for l.pos < len(l.src) {
+		switch l.src[l.pos] {
+		case '\\':
+			l.pos += 2
+			if l.pos > len(l.src) {
+				l.pos = len(l.src)
+			}
+			continue
+		case '\n':
+			if l.newlineInRule {
+				break
+			}
+			if l.src[l.pos-1] == '\r' {
+				l.pos-- // leave a CRLF line ending out of the part
+			}
+			return false
+		case delim:
+			l.pos++
+			return true
+		}
+		l.pos++
+	}
+	return false
+}
+
+// atBoundary reports whether the lexer is at the end of a rule: the end of
+// input, whitespace, a separator or a brace.
+func (l *lexer) atBoundary() bool {
+	if l.pos >= len(l.src) {
+		return true
+	}
+	switch ch := l.src[l.pos]; ch {
+	case ';', '{', '}':
+		return true
+	default:
+		return isSpace(ch)
+	}
+}
+
+func isLetter(ch byte) bool { return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' }
+func isDigit(ch byte) bool  { return ch >= '0' && ch <= '9' }
+func isSpace(ch byte) bool  { return ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n' }
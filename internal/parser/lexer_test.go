@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// tokenTexts returns the text of each token.
+func tokenTexts(toks []token) []string {
+	var texts []string
+	for _, tok := range toks {
+		texts = append(texts, tok.text)
+	}
+	return texts
+}
+
+func TestLex(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{"one rule", "s/a/b/g", []string{"s/a/b/g"}},
+		{"several per line", "s/a/b/ p/x/i d/y/", []string{"s/a/b/", "p/x/i", "d/y/"}},
+		{"semicolons", "sort;reverse; join/,/", []string{"sort", "reverse", "join/,/"}},
+		{"comments", "# header\np/x/ # trailing\n#p/y/\n", []string{"p/x/"}},
+		{"block over lines", "if/x/ {\n  s/a/b/\n  sort\n}\n", []string{"if/x/", "{", "s/a/b/", "sort", "}"}},
+		{"block on one line", "if/x/{s/a/b/}", []string{"if/x/", "{", "s/a/b/", "}"}},
+		{"delimited parts keep specials", "s/a b;c{#}/d/ p|x y|", []string{"s/a b;c{#}/d/", "p|x y|"}},
+		{"escaped delimiter", `s/a\/b/c/ d/x/`, []string{`s/a\/b/c/`, "d/x/"}},
+		{"unterminated part runs to end of line", "s/a/b c\np/x", []string{"s/a/b c", "p/x"}},
+		{"crlf", "s/a/b\r\np/x/\r\n", []string{"s/a/b", "p/x/"}},
+		{"line numbers", "d:2 p:1-5,7 s:3:three: s:4:x y", []string{"d:2", "p:1-5,7", "s:3:three:", "s:4:x y"}},
+		{"bare join", "join sort", []string{"join", "sort"}},
+		{"between", "!between/a/b/i { d/x/ }", []string{"!between/a/b/i", "{", "d/x/", "}"}},
+		{"single quotes", `'s/a b/c/' "p/\"q\"/"`, []string{"s/a b/c/", `p/"q"/`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toks, err := lex(tt.src, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := tokenTexts(toks); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLex_Errors(t *testing.T) {
+	tests := []struct {
+		src    string
+		offset int
+	}{
+		{"bogus/x/", 0},
+		{"p/x/ frob", 5},
+		{"sxaxbx", 0},
+		{"s", 1},
+		{"p a", 1},
+		{"s/a\nsort", 3},
+		{"sort/x/", 4},
+		{"s:x:y", 2},
+		{"'p/x/", 0},
+		{"p/x/ @", 5},
+	}
+	for _, tt := range tests {
+		_, err := lex(tt.src, nil)
+		var perr *Error
+		if !errors.As(err, &perr) {
+			t.Errorf("%q: got %v, want an *Error", tt.src, err)
+			continue
+		}
+		if perr.Offset != tt.offset {
+			t.Errorf("%q: error %q at offset %d, want %d", tt.src, err, perr.Offset, tt.offset)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Macro is a named piece of script that can be used like a command. Using
+// it expands to the rules of its body, with each %{param} in the body
+// replaced by the matching argument:
+//
+//	trimws = s/\s+$//
+//	rename(from, to) = s/%{from}/%{to}/g
+//
+// trimws takes no arguments and is used like sort; rename takes two, given
+// as delimited parts like any other command: rename/foo/bar/. Arguments
+// are substituted as text, so the body decides how they are matched.
+type Macro struct {
+	Name   string
+	Params []string
+	Body   string
+}
+
+// String formats the macro the way it is defined.
+func (m *Macro) String() string {
+	name := m.Name
+	if len(m.Params) > 0 {
+		name += "(" + strings.Join(m.Params, ", ") + ")"
+	}
+	return name + " = " + strings.ReplaceAll(m.Body, "\n", "\n  ")
+}
+
+// paramRef matches a parameter reference in a macro body.
+var paramRef = regexp.MustCompile(`%\{(\w+)\}`)
+
+// macroHead matches the start of a macro definition, up to the '='.
+var macroHead = regexp.MustCompile(`^(\w+)\s*(?:\(([^)]*)\))?\s*=`)
+
+// ParseMacros parses macro definitions, one per line:
+//
+//	name = body
+//	name(param, ...) = body
+//
+// Lines that start with whitespace continue the previous definition, so a
+// body can span lines like a script. Blank lines and lines starting with
+// '#' are ignored. Errors are *Error values giving the line and column.
+//
+// Bodies are only checked for undefined parameters here; the rules in
+// them are parsed when the macro is used.
+func ParseMacros(name, src string) ([]*Macro, error) {
+	var macros []*Macro
+	offsets := map[*Macro]int{} // where each macro is defined
+	seen := map[string]bool{}
+	offset := 0
+	for _, line := range strings.SplitAfter(src, "\n") {
+		lineOffset := offset
+		offset += len(line)
+		line = strings.TrimRight(line, "\r\n")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			if len(macros) == 0 {
+				return nil, macroError(name, src, lineOffset, "continuation line without a macro to continue")
+			}
+			last := macros[len(macros)-1]
+			last.Body += "\n" + strings.TrimSpace(line)
+			continue
+		}
+
+		head := macroHead.FindStringSubmatch(line)
+		if head == nil {
+			return nil, macroError(name, src, lineOffset, "expected a macro definition, as in name = rules")
+		}
+		m := &Macro{Name: head[1], Body: strings.TrimSpace(line[len(head[0]):])}
+		if err := checkMacroName(m.Name, seen); err != nil {
+			return nil, macroError(name, src, lineOffset, "%v", err)
+		}
+		if strings.TrimSpace(head[2]) != "" {
+			for _, param := range strings.Split(head[2], ",") {
+				m.Params = append(m.Params, strings.TrimSpace(param))
+			}
+		}
+		if err := checkParams(m.Params); err != nil {
+			return nil, macroError(name, src, lineOffset, "macro %s: %v", m.Name, err)
+		}
+		seen[m.Name] = true
+		offsets[m] = lineOffset
+		macros = append(macros, m)
+	}
+
+	for _, m := range macros {
+		for _, ref := range paramRef.FindAllStringSubmatch(m.Body, -1) {
+			if !containsString(m.Params, ref[1]) {
+				return nil, macroError(name, src, offsets[m], "macro %s uses %%{%s}, which is not one of its parameters", m.Name, ref[1])
+			}
+		}
+	}
+	return macros, nil
+}
+
+// macroError returns an Error at offset in a macro file.
+func macroError(name, src string, offset int, format string, args ...any) error {
+	err := &Error{Err: fmt.Errorf(format, args...), Offset: offset}
+	err.locate(name, src)
+	return err
+}
+
+// checkMacroName reports whether name can be defined as a macro: it must
+// be a word of letters, not already defined and not a built-in command.
+func checkMacroName(name string, seen map[string]bool) error {
+	for i := 0; i < len(name); i++ {
+		if !isLetter(name[i]) {
+			return fmt.Errorf("invalid macro name %q: names are made of letters", name)
+		}
+	}
+	if _, ok := commands[name]; ok {
+		return fmt.Errorf("macro %s would hide the built-in command", name)
+	}
+	if seen[name] {
+		return fmt.Errorf("macro %s is already defined", name)
+	}
+	return nil
+}
+
+// checkParams reports a missing or repeated parameter name.
+func checkParams(params []string) error {
+	for i, param := range params {
+		if param == "" {
+			return fmt.Errorf("empty parameter name")
+		}
+		if containsString(params[:i], param) {
+			return fmt.Errorf("parameter %s is repeated", param)
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// expander replaces uses of macros in a list of tokens with the tokens of
+// their bodies.
+type expander struct {
+	macros map[string]*Macro
+}
+
+// expand expands the macros in toks. stack holds the macros being expanded,
+// to catch a macro that uses itself.
+func (x *expander) expand(toks []token, stack []string) ([]token, error) {
+	if len(x.macros) == 0 {
+		return toks, nil
+	}
+	var expanded []token
+	for _, tok := range toks {
+		m := x.lookup(tok)
+		if m == nil {
+			expanded = append(expanded, tok)
+			continue
+		}
+		body, err := x.expandOne(m, tok, stack)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, body...)
+	}
+	return expanded, nil
+}
+
+// lookup returns the macro tok uses, if any.
+func (x *expander) lookup(tok token) *Macro {
+	if tok.kind != tokenRule {
+		return nil
+	}
+	end := 0
+	for end < len(tok.text) && isLetter(tok.text[end]) {
+		end++
+	}
+	return x.macros[tok.text[:end]]
+}
+
+// expandOne expands one use of m, returning its body's tokens placed at tok.
+func (x *expander) expandOne(m *Macro, tok token, stack []string) ([]token, error) {
+	errorf := func(format string, args ...any) error {
+		return &Error{Err: fmt.Errorf(format, args...), arg: tok.arg, Offset: tok.offset, Rule: tok.text}
+	}
+	if containsString(stack, m.Name) {
+		return nil, errorf("macro %s uses itself: %s -> %s", m.Name, strings.Join(stack, " -> "), m.Name)
+	}
+
+	args := macroArgs(tok.text[len(m.Name):], len(m.Params))
+	if len(args) != len(m.Params) {
+		return nil, errorf("macro %s takes %d arguments, as in %s", m.Name, len(m.Params), macroUsage(m))
+	}
+	body := paramRef.ReplaceAllStringFunc(m.Body, func(ref string) string {
+		name := ref[2 : len(ref)-1]
+		for i, param := range m.Params {
+			if param == name {
+				return args[i]
+			}
+		}
+		return ref
+	})
+
+	bodyToks, err := lex(body, x.macros)
+	if err != nil {
+		return nil, errorf("in macro %s: %v", m.Name, unwrapError(err))
+	}
+	for i := range bodyToks {
+		bodyToks[i].arg, bodyToks[i].offset = tok.arg, tok.offset
+		if bodyToks[i].macro == "" {
+			bodyToks[i].macro = m.Name
+		}
+	}
+	return x.expand(bodyToks, append(stack, m.Name))
+}
+
+// macroArgs splits the delimited arguments of a macro use. Escapes are
+// kept as written, since the arguments are substituted into a body that
+// is parsed again. The last argument may leave out its closing delimiter.
+func macroArgs(rest string, n int) []string {
+	if n == 0 {
+		if rest != "" {
+			return []string{rest}
+		}
+		return nil
+	}
+	if rest == "" {
+		return nil
+	}
+	delim := rest[0]
+	var args []string
+	start := 1
+	for i := 1; i < len(rest) && len(args) < n; i++ {
+		switch rest[i] {
+		case '\\':
+			i++
+		case delim:
+			args = append(args, rest[start:i])
+			start = i + 1
+		}
+	}
+	if len(args) < n && start < len(rest) {
+		args = append(args, rest[start:])
+	}
+	return args
+}
+
+// macroUsage shows how m is used, for messages.
+func macroUsage(m *Macro) string {
+	usage := m.Name
+	if len(m.Params) > 0 {
+		usage += "/" + strings.Join(m.Params, "/") + "/"
+	}
+	return usage
+}
+
+// unwrapError returns the message of a parse error without its position,
+// which would be relative to the macro body rather than the script.
+func unwrapError(err error) error {
+	if perr, ok := err.(*Error); ok {
+		return perr.Err
+	}
+	return err
+}
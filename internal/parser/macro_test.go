@@ -0,0 +1,141 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/colinta/ged/internal/rule"
+)
+
+const testMacros = `# house style
+trimws = s/\s+$//
+nocomments = d/^\s*#/ d/^\s*$/
+rename(from, to) = s/%{from}/%{to}/g
+tidy = trimws
+  nocomments
+loop = again
+again = loop
+`
+
+func mustParseMacros(t *testing.T) []*Macro {
+	t.Helper()
+	macros, err := ParseMacros("config", testMacros)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return macros
+}
+
+func TestParseMacros(t *testing.T) {
+	macros := mustParseMacros(t)
+	if len(macros) != 6 {
+		t.Fatalf("got %d macros, want 6", len(macros))
+	}
+	if got := macros[2].String(); got != "rename(from, to) = s/%{from}/%{to}/g" {
+		t.Errorf("got %q", got)
+	}
+	if got := macros[3].Body; got != "trimws\nnocomments" {
+		t.Errorf("continued body: got %q", got)
+	}
+}
+
+func TestParseMacros_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"not a definition", "trimws s/x//\n", "config:1:1: expected a macro definition"},
+		{"built-in", "x = sort\nsort = reverse\n", "config:2:1: macro sort would hide the built-in command"},
+		{"duplicate", "a = sort\na = reverse\n", "config:2:1: macro a is already defined"},
+		{"name", "a2 = sort\n", "invalid macro name"},
+		{"repeated parameter", "a(x, x) = s/%{x}//\n", "parameter x is repeated"},
+		{"undefined parameter", "a = sort\nb(x) = s/%{y}//\n", "config:2:1: macro b uses %{y}"},
+		{"stray continuation", "  sort\n", "config:1:1: continuation line"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseMacros("config", tt.src)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseArgs_Macros(t *testing.T) {
+	macros := WithMacros(mustParseMacros(t))
+	tests := []struct {
+		name  string
+		args  []string
+		count int
+	}{
+		{"no arguments", []string{"trimws"}, 1},
+		{"several rules", []string{"nocomments"}, 2},
+		{"arguments", []string{"rename/foo/bar/"}, 1},
+		{"last argument unclosed", []string{"rename/foo/bar"}, 1},
+		{"recursive", []string{"tidy; sort"}, 4},
+		{"in a block", []string{"if/x/ { trimws }"}, 1},
+		{"quoted", []string{"'rename|a|b|'"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := ParseArgs(tt.args, macros)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(rules) != tt.count {
+				t.Errorf("got %d rules, want %d", len(rules), tt.count)
+			}
+		})
+	}
+}
+
+func TestParseArgs_MacroArguments(t *testing.T) {
+	rules, err := ParseArgs([]string{`rename/a\/b/c/`}, WithMacros(mustParseMacros(t)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := rules[0].(rule.LineRule).Apply(nil, "xa/by a/b", &rule.LineContext{})
+	if err != nil || len(got) != 1 || got[0] != "xcy c" {
+		t.Errorf("got %q, want [\"xcy c\"]", got)
+	}
+}
+
+func TestParseArgs_MacroErrors(t *testing.T) {
+	macros := WithMacros(mustParseMacros(t))
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"cycle", []string{"sort; loop"}, "argument 1, column 7: macro loop uses itself: loop -> again -> loop"},
+		{"too few arguments", []string{"'rename/x'"}, "macro rename takes 2 arguments, as in rename/from/to/"},
+		{"bad argument", []string{"rename/(/x/"}, "argument 1, column 1: in macro rename: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseArgs(tt.args, macros)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseArgs_MacrosNeedOption(t *testing.T) {
+	_, err := ParseArgs([]string{"trimws"})
+	if err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Errorf("got %v, want an unknown command error", err)
+	}
+}
+
+func TestParseScript_Macros(t *testing.T) {
+	rules, err := ParseScript("tidy.ged", "tidy\nrename/a/b/\n", WithMacros(mustParseMacros(t)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rules) != 4 {
+		t.Errorf("got %d rules, want 4", len(rules))
+	}
+}
@@ -1,53 +1,175 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/colinta/ged/internal/rule"
 )
 
+// Option configures ParseArgs and ParseScript.
+type Option func(*config)
+
+type config struct {
+	macros   map[string]*Macro
+	ruleOpts []rule.RuleOption // options for every rule
+}
+
+// WithMacros makes macros usable as commands. A later macro replaces an
+// earlier one of the same name.
+func WithMacros(macros []*Macro) Option {
+	return func(c *config) {
+		if c.macros == nil {
+			c.macros = map[string]*Macro{}
+		}
+		for _, m := range macros {
+			c.macros[m.Name] = m
+		}
+	}
+}
+
+// WithMatchTimeout limits how long any one regex match may run; see
+// rule.WithMatchTimeout.
+func WithMatchTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.ruleOpts = append(c.ruleOpts, rule.WithMatchTimeout(d))
+	}
+}
+
+// WithDialect sets the regex dialect for every pattern. A rule's own
+// dialect flag (e, n or r) overrides it.
+func WithDialect(d rule.Dialect) Option {
+	return func(c *config) {
+		c.ruleOpts = append(c.ruleOpts, rule.WithDialect(d))
+	}
+}
+
+func buildConfig(opts []Option) config {
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
 // ParseArgs parses a list of CLI arguments into rules, handling { } blocks
 // for conditional rules. Returns a flat list of LineRule and DocumentRule values.
-func ParseArgs(args []string) ([]any, error) {
-	rules, remaining, err := parseArgs(args)
+// Each argument is lexed like a line of a script, so one argument can hold
+// several rules separated by ';', and braces can be inside it or separate
+// arguments: 'if/foo/ { s/a/b/g; sort }; d/^#/'. Errors are *Error values
+// giving the argument and column.
+func ParseArgs(args []string, opts ...Option) ([]any, error) {
+	cfg := buildConfig(opts)
+	toks, err := lexArgs(args, cfg.macros)
+	var rules []any
+	if err == nil {
+		end := token{}
+		if len(args) > 0 {
+			end = token{arg: len(args) - 1, offset: len(args[len(args)-1])}
+		}
+		rules, err = parseAll(toks, end, cfg)
+	}
+	if err != nil {
+		var perr *Error
+		if errors.As(err, &perr) {
+			perr.locateArg(args)
+		}
+		return nil, err
+	}
+	return rules, nil
+}
+
+// ParseScript parses the text of a script file into rules, like ParseArgs.
+// Rules may be spread over several lines or share one, blocks may span
+// lines, and '#' starts a comment; see lexer for the details. Errors are
+// *Error values giving the script name, line and column.
+func ParseScript(name, src string, opts ...Option) ([]any, error) {
+	cfg := buildConfig(opts)
+	toks, err := lex(src, cfg.macros)
+	var rules []any
+	if err == nil {
+		rules, err = parseAll(toks, token{offset: len(src)}, cfg)
+	}
+	if err != nil {
+		var perr *Error
+		if errors.As(err, &perr) {
+			perr.locate(name, src)
+		}
+		return nil, err
+	}
+	return rules, nil
+}
+
+// parseAll parses a complete list of tokens. end marks the end of the
+// source, where errors about missing tokens are reported.
+func parseAll(toks []token, end token, cfg config) ([]any, error) {
+	x := &expander{macros: cfg.macros}
+	toks, err := x.expand(toks, nil)
+	if err != nil {
+		return nil, err
+	}
+	p := &tokenParser{end: end, ruleOpts: cfg.ruleOpts}
+	rules, remaining, err := p.parse(toks)
 	if err != nil {
 		return nil, err
 	}
 	if len(remaining) > 0 {
-		return nil, fmt.Errorf("unexpected '%s'", remaining[0])
+		return nil, p.errorAt(remaining, fmt.Errorf("unexpected '%s'", remaining[0].text))
 	}
 	return rules, nil
 }
 
-// parseArgs is the recursive workhorse. It consumes args until it hits "}" or
-// runs out of input. Returns the parsed rules and any unconsumed args.
+// tokenParser assembles tokens into rules.
+type tokenParser struct {
+	end      token // position of the end of the source
+	ruleOpts []rule.RuleOption
+}
+
+// errorAt returns an Error at the first of toks, or at the end of the
+// source if toks is empty.
+func (p *tokenParser) errorAt(toks []token, err error) *Error {
+	at := p.end
+	if len(toks) > 0 {
+		at = toks[0]
+	}
+	if at.macro != "" {
+		err = fmt.Errorf("in macro %s: %w", at.macro, err)
+	}
+	return &Error{Err: err, arg: at.arg, Offset: at.offset}
+}
+
+// parse is the recursive workhorse. It consumes tokens until it hits "}"
+// or runs out of input. Returns the parsed rules and any unconsumed tokens.
 //
 // When it encounters a condition (from "if/pattern/"), it expects "{" next,
 // then recurses to collect inner rules, then expects "}".
-func parseArgs(args []string) ([]any, []string, error) {
+func (p *tokenParser) parse(toks []token) ([]any, []token, error) {
 	var results []any
 
-	for len(args) > 0 {
-		if args[0] == "}" {
+	for len(toks) > 0 {
+		if toks[0].kind == tokenClose {
 			// End of block — return so the caller can consume "}"
-			return results, args, nil
+			return results, toks, nil
 		}
-		if args[0] == "{" {
-			return nil, nil, fmt.Errorf("unexpected '{'")
+		if toks[0].kind == tokenOpen {
+			return nil, nil, p.errorAt(toks, fmt.Errorf("unexpected '{'"))
 		}
 
-		parsed, err := ParseRule(args[0])
+		parsed, err := ParseRule(toks[0].text, p.ruleOpts...)
 		if err != nil {
-			return nil, nil, err
+			perr := p.errorAt(toks, err)
+			perr.Rule = toks[0].text
+			return nil, nil, perr
 		}
-		args = args[1:]
+		toks = toks[1:]
 
 		if cond, ok := parsed.(*condition); ok {
-			innerParsed, remaining, err := collectBlock(args, "if condition")
+			innerParsed, remaining, err := p.collectBlock(toks, "if condition")
 			if err != nil {
 				return nil, nil, err
 			}
-			args = remaining
+			toks = remaining
 
 			if hasDocRule(innerParsed) {
 				docRules := buildDocRules(innerParsed)
@@ -60,11 +182,11 @@ func parseArgs(args []string) ([]any, []string, error) {
 				results = append(results, rule.NewConditionalLineRule(cond.pattern, cond.inverted, lineRules))
 			}
 		} else if cond, ok := parsed.(*betweenCondition); ok {
-			innerParsed, remaining, err := collectBlock(args, "between condition")
+			innerParsed, remaining, err := p.collectBlock(toks, "between condition")
 			if err != nil {
 				return nil, nil, err
 			}
-			args = remaining
+			toks = remaining
 
 			if hasDocRule(innerParsed) {
 				docRules := buildDocRules(innerParsed)
@@ -81,23 +203,23 @@ func parseArgs(args []string) ([]any, []string, error) {
 		}
 	}
 
-	return results, args, nil
+	return results, toks, nil
 }
 
-// collectBlock consumes "{", inner rules, and "}" from args.
-// Returns the inner rules and the remaining args after "}".
-func collectBlock(args []string, context string) ([]any, []string, error) {
-	if len(args) == 0 || args[0] != "{" {
-		return nil, nil, fmt.Errorf("expected '{' after %s", context)
+// collectBlock consumes "{", inner rules, and "}" from toks.
+// Returns the inner rules and the remaining tokens after "}".
+func (p *tokenParser) collectBlock(toks []token, context string) ([]any, []token, error) {
+	if len(toks) == 0 || toks[0].kind != tokenOpen {
+		return nil, nil, p.errorAt(toks, fmt.Errorf("expected '{' after %s", context))
 	}
-	args = args[1:] // consume "{"
+	toks = toks[1:] // consume "{"
 
-	innerParsed, remaining, err := parseArgs(args)
+	innerParsed, remaining, err := p.parse(toks)
 	if err != nil {
 		return nil, nil, err
 	}
-	if len(remaining) == 0 || remaining[0] != "}" {
-		return nil, nil, fmt.Errorf("expected '}'")
+	if len(remaining) == 0 || remaining[0].kind != tokenClose {
+		return nil, nil, p.errorAt(remaining, fmt.Errorf("expected '}'"))
 	}
 	return innerParsed, remaining[1:], nil
 }
@@ -1,7 +1,9 @@
 package parser
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/colinta/ged/internal/rule"
 )
@@ -139,6 +141,57 @@ func TestParseArgs_MixedRules(t *testing.T) {
 	}
 }
 
+func TestParseArgs_SingleArgumentScript(t *testing.T) {
+	results, err := ParseArgs([]string{"if/foo/ { s/a/b/g; sort }; d/^#/"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(results))
+	}
+	if _, ok := results[0].(rule.DocumentRule); !ok {
+		t.Errorf("expected DocumentRule, got %T", results[0])
+	}
+}
+
+func TestParseArgs_BracesSplitAcrossArguments(t *testing.T) {
+	// Braces may share an argument with rules or stand alone
+	results, err := ParseArgs([]string{"if/x/ {", "s/a/b/", "}; s/c/d/"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(results))
+	}
+}
+
+func TestParseArgs_NewlineInRule(t *testing.T) {
+	// The shell has already split the arguments, so a newline inside one
+	// is part of the rule
+	results, err := ParseArgs([]string{"s/\n/ /g"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sub := results[0].(*rule.SubstitutionRule)
+	got, err := sub.Apply(nil, "a\nb", &rule.LineContext{})
+	if err != nil || len(got) != 1 || got[0] != "a b" {
+		t.Errorf("got %q, want [\"a b\"]", got)
+	}
+}
+
+func TestParseArgs_WithMatchTimeout(t *testing.T) {
+	// The timeout reaches rules nested in blocks
+	results, err := ParseArgs([]string{"if/a/ { s/(a+)+$/x/ }"}, WithMatchTimeout(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	line := strings.Repeat("a", 40) + "!"
+	_, err = results[0].(rule.LineRule).Apply(nil, line, &rule.LineContext{LineNum: 1})
+	if err == nil || !strings.Contains(err.Error(), "s/(a+)+$/x/") {
+		t.Errorf("got %v, want a timeout in s/(a+)+$/x/", err)
+	}
+}
+
 func TestParseArgs_MissingOpenBrace(t *testing.T) {
 	_, err := ParseArgs([]string{"if/hello/", "s/a/b/"})
 	if err == nil {
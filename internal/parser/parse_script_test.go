@@ -0,0 +1,161 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/colinta/ged/internal/rule"
+)
+
+func TestParseScript(t *testing.T) {
+	src := `# Tidy the changelog
+d/^\s*$/          # drop blank lines
+if/^## / {
+  s/## /# /
+  s/ +$//g
+}
+sort; reverse
+`
+	rules, err := ParseScript("tidy.ged", src)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rules) != 4 {
+		t.Fatalf("got %d rules, want 4", len(rules))
+	}
+	if _, ok := rules[1].(*rule.ConditionalLineRule); !ok {
+		t.Errorf("rules[1]: got %T, want *rule.ConditionalLineRule", rules[1])
+	}
+	if _, ok := rules[3].(*rule.ReverseRule); !ok {
+		t.Errorf("rules[3]: got %T, want *rule.ReverseRule", rules[3])
+	}
+}
+
+func TestParseScript_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		line int
+		col  int
+		msg  string
+	}{
+		{"unknown command", "p/x/\n  frob/y/\n", 2, 3, `unknown command "frob"`},
+		{"invalid regex", "sort\np/(/\n", 2, 1, "missing closing )"},
+		{"missing block", "if/x/\n", 2, 1, "expected '{' after if condition"},
+		{"unclosed block", "if/x/ {\n  sort\n", 3, 1, "expected '}'"},
+		{"stray brace", "sort }", 1, 6, "unexpected '}'"},
+		{"columns count characters", "s/é/e/ ✓", 1, 8, "unexpected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseScript("x.ged", tt.src)
+			var perr *Error
+			if !errors.As(err, &perr) {
+				t.Fatalf("got %v, want an *Error", err)
+			}
+			if perr.Name != "x.ged" || perr.Line != tt.line || perr.Col != tt.col {
+				t.Errorf("got %s:%d:%d, want x.ged:%d:%d", perr.Name, perr.Line, perr.Col, tt.line, tt.col)
+			}
+			if !strings.Contains(err.Error(), tt.msg) || !strings.HasPrefix(err.Error(), "x.ged:") {
+				t.Errorf("got %q, want x.ged:... containing %q", err, tt.msg)
+			}
+		})
+	}
+}
+
+func TestParseArgs_ErrorPosition(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"rule argument", []string{"s/a/b/", "if/x/", "sort"}, "argument 3, column 1: expected '{' after if condition"},
+		{"inside an argument", []string{"s/a/b/; zap/x/"}, "argument 1, column 9: unknown command"},
+		{"missing close brace", []string{"if/x/ { sort"}, "argument 1, column 13: expected '}'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseArgs(tt.args)
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("got %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestError_Caret(t *testing.T) {
+	tests := []struct {
+		name string
+		err  func() error
+		want string
+	}{
+		{"script line", func() error { _, err := ParseScript("", "sort\n  frob/y/\n"); return err },
+			"    frob/y/\n    ^"},
+		{"argument", func() error { _, err := ParseArgs([]string{"sort", "s/a/b/; revers"}); return err },
+			"  s/a/b/; revers\n          ^"},
+		{"line of an argument", func() error { _, err := ParseArgs([]string{"sort\n\tp/(/"}); return err },
+			"  \tp/(/\n  \t^"},
+		{"end of argument", func() error { _, err := ParseArgs([]string{"if/x/ { sort"}); return err },
+			"  if/x/ { sort\n              ^"},
+		{"end of script", func() error { _, err := ParseScript("", "if/x/ {\n"); return err }, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var perr *Error
+			if err := tt.err(); !errors.As(err, &perr) {
+				t.Fatalf("got %v, want an *Error", err)
+			}
+			if got := perr.Caret(); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestError_Fields(t *testing.T) {
+	_, err := ParseArgs([]string{"sort", "s/a/b/;  p/(/g"})
+	var perr *Error
+	if !errors.As(err, &perr) {
+		t.Fatalf("got %v, want an *Error", err)
+	}
+	if perr.Arg != 2 || perr.Offset != 9 || perr.Col != 10 {
+		t.Errorf("got argument %d, offset %d, column %d; want 2, 9, 10", perr.Arg, perr.Offset, perr.Col)
+	}
+	if perr.Rule != "p/(/g" || perr.Text != "s/a/b/;  p/(/g" {
+		t.Errorf("got rule %q in %q", perr.Rule, perr.Text)
+	}
+}
+
+func TestParseArgs_Suggestions(t *testing.T) {
+	macros := []*Macro{{Name: "strip", Body: `s/^\s+//`}}
+	tests := []struct {
+		name string
+		want string // the suggestion, or "" for none
+	}{
+		{"revers", "reverse"},
+		{"srot", "sort"},
+		{"sorted", "sort"},
+		{"!betwen", "!between"},
+		{"stirp", "strip"},
+		{"frob", ""},
+		{"x", ""},
+		{"zap", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseArgs([]string{tt.name}, WithMacros(macros))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			hint := "did you mean"
+			if tt.want == "" {
+				if strings.Contains(err.Error(), hint) {
+					t.Errorf("got %q, want no suggestion", err)
+				}
+			} else if !strings.HasSuffix(err.Error(), `; did you mean "`+tt.want+`"?`) {
+				t.Errorf("got %q, want a suggestion of %q", err, tt.want)
+			}
+		})
+	}
+}
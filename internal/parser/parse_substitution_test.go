@@ -132,3 +132,36 @@ func TestParseRule_SubstitutionLineNum(t *testing.T) {
 		})
 	}
 }
+
+func TestParseRule_DialectFlags(t *testing.T) {
+	re2 := rule.WithDialect(rule.RE2)
+	tests := []struct {
+		name    string
+		input   string
+		base    []rule.RuleOption
+		wantErr bool
+	}{
+		// RE2 has no backreferences, so the pattern shows the dialect used
+		{"default", `s/(a)\1/x/`, nil, false},
+		{"re2 flag", `s/(a)\1/x/r`, nil, true},
+		{"re2 flag with global", `s/(a)\1/x/gr`, nil, true},
+		{"global re2", `s/(a)\1/x/`, []rule.RuleOption{re2}, true},
+		{"flag overrides global", `s/(a)\1/x/e`, []rule.RuleOption{re2}, false},
+		{"last flag wins", `s/(a)\1/x/rn`, nil, false},
+		{"dotnet flag", `p/\p{Lu}/n`, nil, false},
+		{"if re2 flag", `if/(a)\1/r`, nil, true},
+		{"control re2 flag", `on/(a)\1/r`, nil, true},
+		{"literal in re2", "p'a b.c'r", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRule(tt.input, tt.base...)
+			if tt.wantErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -3,16 +3,17 @@ package parser
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/colinta/ged/internal/rule"
-	"github.com/dlclark/regexp2"
 )
 
 // ParseRule parses a rule string and returns the appropriate Rule.
 // It handles delimiter detection and dispatches to command-specific parsers.
 // Returns either a rule.LineRule or rule.DocumentRule (as any).
-func ParseRule(input string) (any, error) {
+// opts apply to every pattern the rule compiles, before the rule's own flags.
+func ParseRule(input string, opts ...rule.RuleOption) (any, error) {
 	// Word commands must be checked first — "sort" starts with 's',
 	// which would otherwise match the substitution command.
 	if input == "sort" {
@@ -25,22 +26,22 @@ func ParseRule(input string) (any, error) {
 		return parseJoin(input)
 	}
 	if strings.HasPrefix(input, "!between") || strings.HasPrefix(input, "between") {
-		return parseBetween(input)
+		return parseBetween(input, opts)
 	}
 	if strings.HasPrefix(input, "!if") || strings.HasPrefix(input, "if") {
-		return parseIf(input)
+		return parseIf(input, opts)
 	}
 	if strings.HasPrefix(input, "on") {
-		return parseControl(input, "on")
+		return parseControl(input, "on", opts)
 	}
 	if strings.HasPrefix(input, "off") {
-		return parseControl(input, "off")
+		return parseControl(input, "off", opts)
 	}
 	if strings.HasPrefix(input, "after") {
-		return parseControl(input, "after")
+		return parseControl(input, "after", opts)
 	}
 	if strings.HasPrefix(input, "toggle") {
-		return parseControl(input, "toggle")
+		return parseControl(input, "toggle", opts)
 	}
 
 	if len(input) < 2 {
@@ -57,23 +58,24 @@ func ParseRule(input string) (any, error) {
 		return nil, err
 	}
 
-	// Quote delimiters mean literal matching — escape regex metacharacters
+	// Quote delimiters mean literal matching — escape regex metacharacters.
+	// QuoteMeta's escapes mean the same in every dialect.
 	if (delimiter == '`' || delimiter == '\'' || delimiter == '"') && len(parts) > 0 {
-		parts[0] = regexp2.Escape(parts[0])
+		parts[0] = regexp.QuoteMeta(parts[0])
 	}
 
 	if command == 'p' && delimiter == ':' {
 		return parsePrintLineNum(parts)
 	} else if command == 'p' {
-		return parsePrint(parts)
+		return parsePrint(parts, opts)
 	} else if command == 'd' && delimiter == ':' {
 		return parseDeleteLineNum(parts)
 	} else if command == 'd' {
-		return parseDelete(parts)
+		return parseDelete(parts, opts)
 	} else if command == 's' && delimiter == ':' {
 		return parseSubstitutionLineNum(parts)
 	} else if command == 's' {
-		return parseSubstitution(parts)
+		return parseSubstitution(parts, opts)
 	} else {
 		return nil, fmt.Errorf("unknown command: %c", command)
 	}
@@ -84,6 +86,9 @@ func ParseRule(input string) (any, error) {
 //
 //	g — global replacement (SubstitutionRule only)
 //	i — case-insensitive matching
+//	e, n, r — compile patterns as ECMAScript, .NET or RE2 (see rule.Dialect)
+//
+// If several dialect flags are given, the last one wins.
 func parseFlags(flags string) []rule.RuleOption {
 	var opts []rule.RuleOption
 	if strings.Contains(flags, "g") {
@@ -92,18 +97,30 @@ func parseFlags(flags string) []rule.RuleOption {
 	if strings.Contains(flags, "i") {
 		opts = append(opts, rule.WithIgnoreCase())
 	}
+	if i := strings.LastIndexAny(flags, "enr"); i >= 0 {
+		opts = append(opts, rule.WithDialect(dialectFlags[flags[i]]))
+	}
 	return opts
 }
 
+// dialectFlags maps each dialect flag to its dialect.
+var dialectFlags = map[byte]rule.Dialect{
+	'e': rule.ECMAScript,
+	'n': rule.DotNet,
+	'r': rule.RE2,
+}
+
 // flagsFromParts extracts flags from the trailing element of a parts slice.
 // For commands like p/pat/ and d/pat/, flags are in parts[1].
 // For substitution s/pat/repl/flags, flags are in parts[2].
-// Returns the options parsed from the given index, or nil if index is out of range.
-func flagsFromParts(parts []string, flagIndex int) []rule.RuleOption {
+// Returns base followed by the options parsed from the given index, or just
+// base if index is out of range.
+func flagsFromParts(base []rule.RuleOption, parts []string, flagIndex int) []rule.RuleOption {
+	opts := append([]rule.RuleOption(nil), base...)
 	if flagIndex < len(parts) {
-		return parseFlags(parts[flagIndex])
+		opts = append(opts, parseFlags(parts[flagIndex])...)
 	}
-	return nil
+	return opts
 }
 
 // parseJoin handles "join" (bare) and "join/sep/" syntax.
@@ -190,14 +207,14 @@ func splitByDelimiter(input string, delimiter byte) ([]string, error) {
 // parseSubstitution creates a SubstitutionRule from parsed parts.
 // Expected parts: [pattern, replacement, flags]
 // The trailing delimiter is required, so we need at least 3 parts.
-func parseSubstitution(parts []string) (*rule.SubstitutionRule, error) {
+func parseSubstitution(parts []string, base []rule.RuleOption) (*rule.SubstitutionRule, error) {
 	if len(parts) < 2 {
 		return nil, fmt.Errorf("substitution requires pattern and replacement with trailing delimiter")
 	}
 
 	pattern := parts[0]
 	replace := parts[1]
-	opts := flagsFromParts(parts, 2)
+	opts := flagsFromParts(base, parts, 2)
 
 	return rule.NewSubstitutionRule(pattern, replace, opts...)
 }
@@ -217,12 +234,12 @@ func parseSubstitutionLineNum(parts []string) (rule.LineRule, error) {
 }
 
 // parsePrint creates a PrintLineRule for pattern matching.
-func parsePrint(parts []string) (rule.LineRule, error) {
+func parsePrint(parts []string, base []rule.RuleOption) (rule.LineRule, error) {
 	if len(parts) < 1 {
 		return nil, fmt.Errorf("print requires a pattern")
 	}
 
-	opts := flagsFromParts(parts, 1)
+	opts := flagsFromParts(base, parts, 1)
 	return rule.NewPrintLineRule(parts[0], opts...)
 }
 
@@ -240,12 +257,12 @@ func parsePrintLineNum(parts []string) (rule.LineRule, error) {
 }
 
 // parseDelete creates a DeleteLineRule for pattern matching.
-func parseDelete(parts []string) (rule.LineRule, error) {
+func parseDelete(parts []string, base []rule.RuleOption) (rule.LineRule, error) {
 	if len(parts) < 1 {
 		return nil, fmt.Errorf("delete requires a pattern")
 	}
 
-	opts := flagsFromParts(parts, 1)
+	opts := flagsFromParts(base, parts, 1)
 	return rule.NewDeleteLineRule(parts[0], opts...)
 }
 
@@ -263,7 +280,7 @@ func parseDeleteLineNum(parts []string) (rule.LineRule, error) {
 }
 
 // parseControl parses "name/pattern/" for control rules (on, off, after, toggle).
-func parseControl(input string, name string) (rule.LineRule, error) {
+func parseControl(input string, name string, base []rule.RuleOption) (rule.LineRule, error) {
 	rest := input[len(name):]
 	if len(rest) == 0 {
 		return nil, fmt.Errorf("%s requires a pattern", name)
@@ -281,10 +298,10 @@ func parseControl(input string, name string) (rule.LineRule, error) {
 
 	pattern := parts[0]
 	if delimiter == '`' || delimiter == '\'' || delimiter == '"' {
-		pattern = regexp2.Escape(pattern)
+		pattern = regexp.QuoteMeta(pattern)
 	}
 
-	opts := flagsFromParts(parts, 1)
+	opts := flagsFromParts(base, parts, 1)
 
 	switch name {
 	case "on":
@@ -304,12 +321,12 @@ func parseControl(input string, name string) (rule.LineRule, error) {
 // It's not a rule — it gets converted into a ConditionalRule once the inner
 // rules are collected from the { } block.
 type condition struct {
-	pattern  *regexp2.Regexp
+	pattern  rule.Matcher
 	inverted bool
 }
 
 // parseIf parses "if/pattern/" or "!if/pattern/" and returns a condition.
-func parseIf(input string) (*condition, error) {
+func parseIf(input string, base []rule.RuleOption) (*condition, error) {
 	inverted := false
 	rest := input
 
@@ -336,10 +353,10 @@ func parseIf(input string) (*condition, error) {
 
 	pattern := parts[0]
 	if delimiter == '`' || delimiter == '\'' || delimiter == '"' {
-		pattern = regexp2.Escape(pattern)
+		pattern = regexp.QuoteMeta(pattern)
 	}
 
-	opts := flagsFromParts(parts, 1)
+	opts := flagsFromParts(base, parts, 1)
 	compiled, err := rule.CompilePattern(pattern, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("invalid pattern in if condition: %w", err)
@@ -354,13 +371,13 @@ func parseIf(input string) (*condition, error) {
 // betweenCondition is a parser-internal type representing a parsed between condition.
 // Like condition, it gets assembled with inner rules from { } blocks in parseArgs.
 type betweenCondition struct {
-	startPattern *regexp2.Regexp
-	endPattern   *regexp2.Regexp
+	startPattern rule.Matcher
+	endPattern   rule.Matcher
 	inverted     bool
 }
 
 // parseBetween parses "between/start/end/" or "!between/start/end/" and returns a betweenCondition.
-func parseBetween(input string) (*betweenCondition, error) {
+func parseBetween(input string, base []rule.RuleOption) (*betweenCondition, error) {
 	inverted := false
 	rest := input
 
@@ -388,11 +405,11 @@ func parseBetween(input string) (*betweenCondition, error) {
 	startPattern := parts[0]
 	endPattern := parts[1]
 	if delimiter == '`' || delimiter == '\'' || delimiter == '"' {
-		startPattern = regexp2.Escape(startPattern)
-		endPattern = regexp2.Escape(endPattern)
+		startPattern = regexp.QuoteMeta(startPattern)
+		endPattern = regexp.QuoteMeta(endPattern)
 	}
 
-	opts := flagsFromParts(parts, 2)
+	opts := flagsFromParts(base, parts, 2)
 	startCompiled, err := rule.CompilePattern(startPattern, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("invalid start pattern in between: %w", err)
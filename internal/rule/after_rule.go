@@ -1,11 +1,9 @@
 package rule
 
-import "github.com/dlclark/regexp2"
-
 // AfterRule starts printing after a line matches the pattern.
 // The matching line itself is not printed — printing starts on the next line.
 type AfterRule struct {
-	pattern *regexp2.Regexp
+	pattern Matcher
 }
 
 // NewAfterRule creates a rule that turns printing on after the first matching line.
@@ -18,6 +16,10 @@ func NewAfterRule(patternStr string, opts ...RuleOption) (*AfterRule, error) {
 	return &AfterRule{pattern: pattern}, nil
 }
 
+func (r *AfterRule) String() string { return "after/" + r.pattern.String() + "/" }
+
+func (r *AfterRule) Patterns() []Matcher { return []Matcher{r.pattern} }
+
 // Setup initializes the print state to off — lines are suppressed until after a match.
 // Only sets the initial state if no other control rule has set it first.
 func (r *AfterRule) Setup(ctx *LineContext) {
@@ -28,7 +30,7 @@ func (r *AfterRule) Setup(ctx *LineContext) {
 
 // Apply checks for a pattern match. On the line after a match, printing turns on.
 // The matching line itself stays off.
-func (r *AfterRule) Apply(line string, ctx *LineContext) ([]string, error) {
+func (r *AfterRule) Apply(dst []string, line string, ctx *LineContext) ([]string, error) {
 	if GetState(ctx, r, false) {
 		ctx.Printing = PrintOn
 	}
@@ -37,7 +39,8 @@ func (r *AfterRule) Apply(line string, ctx *LineContext) ([]string, error) {
 		return nil, err
 	}
 	if matched {
+		ctx.Matched = true
 		SetState(ctx, r, true)
 	}
-	return []string{line}, nil
+	return append(dst, line), nil
 }
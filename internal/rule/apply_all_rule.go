@@ -1,9 +1,8 @@
 package rule
 
 // ApplyAllRule wraps a slice of LineRules into a DocumentRule.
-// It applies the line rules as a pipeline to each line of the document.
-// This avoids a circular import with the engine package by inlining
-// the pipeline chaining logic.
+// It applies the line rules as a pipeline to each line of the document,
+// with ApplyRules, as the engine's Pipeline does.
 type ApplyAllRule struct {
 	rules []LineRule
 }
@@ -13,50 +12,48 @@ func NewApplyAllRule(rules []LineRule) *ApplyAllRule {
 	return &ApplyAllRule{rules: rules}
 }
 
+func (r *ApplyAllRule) Inner() []any { return innerRules(r.rules) }
+
 // ApplyDocument applies the line rules pipeline to each line of the document.
 // Each line is processed through all rules in order, with each rule's output
 // feeding into the next rule. Line numbers are 1-indexed.
 // Rules implementing SetupRule have Setup called once before processing.
-// After processing each line, ctx.Printing is checked to decide inclusion.
-func (r *ApplyAllRule) ApplyDocument(lines []string) ([]string, error) {
+// After processing each line, lineCtx.Printing is checked to decide inclusion.
+// The line rules get their own LineContext, so line numbers and print state
+// start fresh; only Matched is reported back to the document's ctx.
+// Cancellation of ctx is checked before each line.
+func (r *ApplyAllRule) ApplyDocument(lines []string, ctx *LineContext) ([]string, error) {
 	var result []string
-	ctx := &LineContext{}
+	lineCtx := &LineContext{RecordSep: ctx.RecordSep, Context: ctx.Context}
+	defer func() {
+		if lineCtx.Matched {
+			ctx.Matched = true
+		}
+	}()
 
 	// Call Setup on any rules that need it
 	for _, lr := range r.rules {
 		if s, ok := lr.(SetupRule); ok {
-			s.Setup(ctx)
+			s.Setup(lineCtx)
 		}
 	}
 
 	for i, line := range lines {
-		ctx.LineNum = i + 1
+		lineCtx.LineNum = i + 1
+		if err := cancelled(ctx, lineCtx.LineNum); err != nil {
+			return nil, err
+		}
 		// Process this line through all rules
-		current := []string{line}
-
-		for _, lr := range r.rules {
-			var next []string
-			for _, l := range current {
-				out, err := lr.Apply(l, ctx)
-				if err != nil {
-					return nil, err
-				}
-				next = append(next, out...)
-			}
-
-			if len(next) == 0 {
-				current = nil
-				break
-			}
-			current = next
+		before := len(result)
+		var err error
+		if result, err = ApplyRules(result, r.rules, line, lineCtx); err != nil {
+			return nil, err
 		}
 
 		// Check print state after processing
-		if ctx.Printing == PrintOff {
-			continue
+		if lineCtx.Printing == PrintOff {
+			result = result[:before]
 		}
-
-		result = append(result, current...)
 	}
 
 	return result, nil
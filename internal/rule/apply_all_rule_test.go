@@ -8,7 +8,7 @@ func TestApplyAllRule_AppliesSubstitution(t *testing.T) {
 	sub, _ := NewSubstitutionRule("foo", "bar")
 	r := NewApplyAllRule([]LineRule{sub})
 
-	result, err := r.ApplyDocument([]string{"foo baz", "hello foo"})
+	result, err := r.ApplyDocument([]string{"foo baz", "hello foo"}, &LineContext{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -28,7 +28,7 @@ func TestApplyAllRule_FiltersLines(t *testing.T) {
 	print, _ := NewPrintLineRule("keep")
 	r := NewApplyAllRule([]LineRule{print})
 
-	result, err := r.ApplyDocument([]string{"keep this", "drop this", "keep that"})
+	result, err := r.ApplyDocument([]string{"keep this", "drop this", "keep that"}, &LineContext{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -49,7 +49,7 @@ func TestApplyAllRule_ChainsRules(t *testing.T) {
 	sub, _ := NewSubstitutionRule("hello", "HI", WithGlobal())
 	r := NewApplyAllRule([]LineRule{print, sub})
 
-	result, err := r.ApplyDocument([]string{"hello world", "goodbye", "hello hello"})
+	result, err := r.ApplyDocument([]string{"hello world", "goodbye", "hello hello"}, &LineContext{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -70,7 +70,7 @@ func TestApplyAllRule_PreservesLineNumbering(t *testing.T) {
 	lineNumRule := NewPrintLineNumRule(SingleLine(2))
 	r := NewApplyAllRule([]LineRule{lineNumRule})
 
-	result, err := r.ApplyDocument([]string{"line1", "line2", "line3"})
+	result, err := r.ApplyDocument([]string{"line1", "line2", "line3"}, &LineContext{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -88,7 +88,7 @@ func TestApplyAllRule_EmptyInput(t *testing.T) {
 	sub, _ := NewSubstitutionRule("foo", "bar")
 	r := NewApplyAllRule([]LineRule{sub})
 
-	result, err := r.ApplyDocument([]string{})
+	result, err := r.ApplyDocument([]string{}, &LineContext{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -97,3 +97,23 @@ func TestApplyAllRule_EmptyInput(t *testing.T) {
 		t.Errorf("got %v, want empty", result)
 	}
 }
+
+func TestApplyAllRule_ReportsMatched(t *testing.T) {
+	print, _ := NewPrintLineRule("keep")
+	r := NewApplyAllRule([]LineRule{print})
+
+	ctx := &LineContext{}
+	if _, err := r.ApplyDocument([]string{"drop this"}, ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ctx.Matched {
+		t.Error("expected Matched=false when no line matched")
+	}
+
+	if _, err := r.ApplyDocument([]string{"drop this", "keep this"}, ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ctx.Matched {
+		t.Error("expected Matched=true when a line matched")
+	}
+}
@@ -1,6 +1,13 @@
 package rule
 
-import "github.com/dlclark/regexp2"
+// betweenString formats a between rule for messages.
+func betweenString(start, end Matcher, inverted bool) string {
+	name := "between"
+	if inverted {
+		name = "!between"
+	}
+	return name + "/" + start.String() + "/" + end.String() + "/"
+}
 
 // betweenState tracks whether we are currently inside a start/end range.
 type betweenState struct {
@@ -13,14 +20,14 @@ type betweenState struct {
 // State is stored on LineContext via GetState/SetState so the rule is reusable
 // across multiple documents.
 type BetweenLineRule struct {
-	startPattern *regexp2.Regexp
-	endPattern   *regexp2.Regexp
+	startPattern Matcher
+	endPattern   Matcher
 	inverted     bool
 	rules        []LineRule
 }
 
 // NewBetweenLineRule creates a BetweenLineRule.
-func NewBetweenLineRule(startPattern, endPattern *regexp2.Regexp, inverted bool, rules []LineRule) *BetweenLineRule {
+func NewBetweenLineRule(startPattern, endPattern Matcher, inverted bool, rules []LineRule) *BetweenLineRule {
 	return &BetweenLineRule{
 		startPattern: startPattern,
 		endPattern:   endPattern,
@@ -29,9 +36,17 @@ func NewBetweenLineRule(startPattern, endPattern *regexp2.Regexp, inverted bool,
 	}
 }
 
+func (r *BetweenLineRule) String() string {
+	return betweenString(r.startPattern, r.endPattern, r.inverted)
+}
+
+func (r *BetweenLineRule) Patterns() []Matcher { return []Matcher{r.startPattern, r.endPattern} }
+
+func (r *BetweenLineRule) Inner() []any { return innerRules(r.rules) }
+
 // Apply checks whether the current line is inside a between range,
 // applying inner rules if so (or if inverted, applying when outside).
-func (r *BetweenLineRule) Apply(line string, ctx *LineContext) ([]string, error) {
+func (r *BetweenLineRule) Apply(dst []string, line string, ctx *LineContext) ([]string, error) {
 	bs := GetState(ctx, r, betweenState{})
 
 	// Check for start/end transitions
@@ -61,29 +76,15 @@ func (r *BetweenLineRule) Apply(line string, ctx *LineContext) ([]string, error)
 		closingThisLine = matched
 	}
 
-	var result []string
-	var err error
 	if active {
+		ctx.Matched = true
 		// Apply inner rules as a pipeline
-		current := []string{line}
-		for _, innerRule := range r.rules {
-			var next []string
-			for _, l := range current {
-				out, err := innerRule.Apply(l, ctx)
-				if err != nil {
-					return nil, err
-				}
-				next = append(next, out...)
-			}
-			if len(next) == 0 {
-				return nil, nil
-			}
-			current = next
+		var err error
+		if dst, err = ApplyRules(dst, r.rules, line, ctx); err != nil {
+			return nil, err
 		}
-		result = current
 	} else {
-		result = []string{line}
-		err = nil
+		dst = append(dst, line)
 	}
 
 	if closingThisLine {
@@ -91,21 +92,21 @@ func (r *BetweenLineRule) Apply(line string, ctx *LineContext) ([]string, error)
 		SetState(ctx, r, bs)
 	}
 
-	return result, err
+	return dst, nil
 }
 
 // BetweenDocRule implements DocumentRule. It collects lines inside between
 // ranges into a sub-document, applies inner DocumentRules to that sub-document,
 // then weaves the results back into their original positions.
 type BetweenDocRule struct {
-	startPattern *regexp2.Regexp
-	endPattern   *regexp2.Regexp
+	startPattern Matcher
+	endPattern   Matcher
 	inverted     bool
 	rules        []DocumentRule
 }
 
 // NewBetweenDocRule creates a BetweenDocRule.
-func NewBetweenDocRule(startPattern, endPattern *regexp2.Regexp, inverted bool, rules []DocumentRule) *BetweenDocRule {
+func NewBetweenDocRule(startPattern, endPattern Matcher, inverted bool, rules []DocumentRule) *BetweenDocRule {
 	return &BetweenDocRule{
 		startPattern: startPattern,
 		endPattern:   endPattern,
@@ -114,18 +115,29 @@ func NewBetweenDocRule(startPattern, endPattern *regexp2.Regexp, inverted bool,
 	}
 }
 
+func (r *BetweenDocRule) String() string {
+	return betweenString(r.startPattern, r.endPattern, r.inverted)
+}
+
+func (r *BetweenDocRule) Patterns() []Matcher { return []Matcher{r.startPattern, r.endPattern} }
+
+func (r *BetweenDocRule) Inner() []any { return innerRules(r.rules) }
+
 // ApplyDocument collects lines inside between ranges, applies inner rules,
 // then reconstructs the output.
-func (r *BetweenDocRule) ApplyDocument(lines []string) ([]string, error) {
+func (r *BetweenDocRule) ApplyDocument(lines []string, ctx *LineContext) ([]string, error) {
 	var activeLines []string
 	isActive := make([]bool, len(lines))
 	inside := false
 
 	for i, line := range lines {
+		if err := cancelled(ctx, i+1); err != nil {
+			return nil, err
+		}
 		if !inside {
 			matched, err := r.startPattern.MatchString(line)
 			if err != nil {
-				return nil, err
+				return nil, &LineError{Line: i + 1, Rule: r, Err: err}
 			}
 			if matched {
 				inside = true
@@ -140,12 +152,13 @@ func (r *BetweenDocRule) ApplyDocument(lines []string) ([]string, error) {
 		if active {
 			activeLines = append(activeLines, line)
 			isActive[i] = true
+			ctx.Matched = true
 		}
 
 		if inside {
 			matched, err := r.endPattern.MatchString(line)
 			if err != nil {
-				return nil, err
+				return nil, &LineError{Line: i + 1, Rule: r, Err: err}
 			}
 			if matched {
 				inside = false
@@ -157,7 +170,7 @@ func (r *BetweenDocRule) ApplyDocument(lines []string) ([]string, error) {
 	processed := activeLines
 	for _, dr := range r.rules {
 		var err error
-		processed, err = dr.ApplyDocument(processed)
+		processed, err = dr.ApplyDocument(processed, ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -113,6 +113,22 @@ func TestBetweenLineRule_InnerDeleteRemovesLine(t *testing.T) {
 	}
 }
 
+func TestBetweenLineRule_DeletedEndLineClosesRange(t *testing.T) {
+	del, _ := NewDeleteLineRule("END")
+	r := NewBetweenLineRule(
+		regexp2.MustCompile("START", 0),
+		regexp2.MustCompile("END", 0),
+		false,
+		[]LineRule{del, mustSub(t, "x", "X")},
+	)
+	lines := []string{"START x", "END x", "after x"}
+	got := applyBetweenLine(t, r, lines)
+	want := "START X\nafter x"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
 func TestBetweenLineRule_UnclosedRangeGoesToEnd(t *testing.T) {
 	r := NewBetweenLineRule(
 		regexp2.MustCompile("START", 0),
@@ -136,7 +152,7 @@ func TestBetweenDocRule_SortsInsideRange(t *testing.T) {
 		[]DocumentRule{NewSortRule()},
 	)
 	lines := []string{"before", "START", "c", "a", "b", "END", "after"}
-	got, err := r.ApplyDocument(lines)
+	got, err := r.ApplyDocument(lines, &LineContext{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -154,7 +170,7 @@ func TestBetweenDocRule_Inverted(t *testing.T) {
 		[]DocumentRule{NewSortRule()},
 	)
 	lines := []string{"c", "a", "START", "middle", "END", "b", "d"}
-	got, err := r.ApplyDocument(lines)
+	got, err := r.ApplyDocument(lines, &LineContext{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -181,7 +197,7 @@ func applyBetweenLine(t *testing.T, r *BetweenLineRule, lines []string) string {
 	ctx := &LineContext{}
 	for i, line := range lines {
 		ctx.LineNum = i + 1
-		out, err := r.Apply(line, ctx)
+		out, err := r.Apply(nil, line, ctx)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -0,0 +1,66 @@
+package rule
+
+// lineBuf is a scratch buffer of lines, kept on a LineContext.
+type lineBuf struct {
+	lines []string
+}
+
+// ApplyRules passes line through rules in order, each rule's output lines
+// feeding the next, and appends the last rule's output to dst. Processing
+// stops early if a rule leaves no lines. Errors are wrapped as by ApplyLine.
+// This is the one place rules are chained: the engine's Pipeline,
+// ApplyAllRule and the if and between blocks all use it.
+//
+// Lines between rules are held in scratch buffers on ctx, reused for every
+// line, so once they have grown a line allocates nothing here.
+func ApplyRules(dst []string, rules []LineRule, line string, ctx *LineContext) ([]string, error) {
+	switch len(rules) {
+	case 0:
+		return append(dst, line), nil
+	case 1:
+		return ApplyLine(dst, rules[0], line, ctx)
+	}
+
+	// The lines alternate between two buffers. Rules inside these rules,
+	// such as an if block's, take the next two.
+	in, out := ctx.takeScratch()
+	defer ctx.releaseScratch()
+
+	in.lines = append(in.lines[:0], line)
+	last := len(rules) - 1
+	for _, r := range rules[:last] {
+		out.lines = out.lines[:0]
+		for _, l := range in.lines {
+			var err error
+			if out.lines, err = ApplyLine(out.lines, r, l, ctx); err != nil {
+				return nil, err
+			}
+		}
+		if len(out.lines) == 0 {
+			return dst, nil
+		}
+		in, out = out, in
+	}
+	for _, l := range in.lines {
+		var err error
+		if dst, err = ApplyLine(dst, rules[last], l, ctx); err != nil {
+			return nil, err
+		}
+	}
+	return dst, nil
+}
+
+// takeScratch returns the next two unused scratch buffers.
+func (ctx *LineContext) takeScratch() (*lineBuf, *lineBuf) {
+	for len(ctx.scratch) < ctx.depth+2 {
+		ctx.scratch = append(ctx.scratch, new(lineBuf))
+	}
+	a, b := ctx.scratch[ctx.depth], ctx.scratch[ctx.depth+1]
+	ctx.depth += 2
+	return a, b
+}
+
+// releaseScratch gives back the buffers from the last takeScratch.
+func (ctx *LineContext) releaseScratch() {
+	ctx.depth -= 2
+}
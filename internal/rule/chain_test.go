@@ -0,0 +1,49 @@
+package rule
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyRules(t *testing.T) {
+	split, _ := NewSubstitutionRule(",", "\n", WithGlobal())
+	upper, _ := NewSubstitutionRule("b", "B")
+	del, _ := NewDeleteLineRule("c")
+	// The inner chain runs while the outer one's buffers are in use
+	inner := NewConditionalLineRule(mustCompile(t, "a|b", ECMAScript), false, []LineRule{upper, split})
+
+	tests := []struct {
+		name  string
+		rules []LineRule
+		line  string
+		want  []string
+	}{
+		{"no rules", nil, "a", []string{"kept", "a"}},
+		{"one rule", []LineRule{split}, "a,b", []string{"kept", "a", "b"}},
+		{"fan out", []LineRule{split, upper, del}, "a,b,c", []string{"kept", "a", "B"}},
+		{"stops when empty", []LineRule{del, split}, "c,d", []string{"kept"}},
+		{"nested", []LineRule{split, inner, del}, "b;x,c", []string{"kept", "B;x"}},
+	}
+	for _, tt := range tests {
+		ctx := &LineContext{}
+		got, err := ApplyRules([]string{"kept"}, tt.rules, tt.line, ctx)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+		if ctx.depth != 0 {
+			t.Errorf("%s: %d scratch buffers still in use", tt.name, ctx.depth)
+		}
+	}
+}
+
+func TestAppendRecords(t *testing.T) {
+	ctx := &LineContext{RecordSep: "\x00"}
+	got := ctx.AppendRecords([]string{"x"}, "a\x00b\nc\x00")
+	want := []string{"x", "a", "b\nc", ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
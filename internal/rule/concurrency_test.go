@@ -0,0 +1,68 @@
+package rule
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/dlclark/regexp2"
+)
+
+// TestDocumentRules_ConcurrentUse runs one set of rules over many documents
+// at once, as `ged -j` does, and checks each result against a sequential
+// run. Run with -race to catch state kept on the rules themselves.
+func TestDocumentRules_ConcurrentUse(t *testing.T) {
+	on, err := NewOnRule("start")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := []DocumentRule{
+		NewApplyAllRule([]LineRule{
+			on,
+			NewBetweenLineRule(regexp2.MustCompile("begin", 0), regexp2.MustCompile("end", 0), false,
+				[]LineRule{mustSub(t, "x", "X")}),
+		}),
+		NewConditionalDocRule(regexp2.MustCompile("[0-9]", 0), false,
+			[]DocumentRule{NewSortRule()}),
+		NewBetweenDocRule(regexp2.MustCompile("begin", 0), regexp2.MustCompile("end", 0), true,
+			[]DocumentRule{NewReverseRule()}),
+	}
+
+	apply := func(lines []string) ([]string, error) {
+		ctx := &LineContext{}
+		for _, dr := range rules {
+			var err error
+			if lines, err = dr.ApplyDocument(lines, ctx); err != nil {
+				return nil, err
+			}
+		}
+		return lines, nil
+	}
+
+	docs := make([][]string, 50)
+	want := make([][]string, len(docs))
+	for i := range docs {
+		docs[i] = []string{"skip", "start", fmt.Sprintf("x %d", i%7), "begin x", fmt.Sprintf("x %d", i%3), "end x", "x y"}
+		if want[i], err = apply(docs[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := make([][]string, len(docs))
+	var wg sync.WaitGroup
+	for i := range docs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got[i], _ = apply(docs[i])
+		}()
+	}
+	wg.Wait()
+
+	for i := range docs {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("document %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
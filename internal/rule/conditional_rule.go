@@ -1,18 +1,25 @@
 package rule
 
-import "github.com/dlclark/regexp2"
+// conditionString formats an if rule for messages.
+func conditionString(condition Matcher, inverted bool) string {
+	name := "if"
+	if inverted {
+		name = "!if"
+	}
+	return name + "/" + condition.String() + "/"
+}
 
 // ConditionalLineRule implements LineRule. It applies inner LineRules only to
 // lines matching (or not matching) a condition. Non-matching lines pass through
 // unchanged. Because all inner rules are LineRules, this can stream.
 type ConditionalLineRule struct {
-	condition *regexp2.Regexp
+	condition Matcher
 	inverted  bool
 	rules     []LineRule
 }
 
 // NewConditionalLineRule creates a ConditionalLineRule.
-func NewConditionalLineRule(condition *regexp2.Regexp, inverted bool, rules []LineRule) *ConditionalLineRule {
+func NewConditionalLineRule(condition Matcher, inverted bool, rules []LineRule) *ConditionalLineRule {
 	return &ConditionalLineRule{
 		condition: condition,
 		inverted:  inverted,
@@ -21,7 +28,7 @@ func NewConditionalLineRule(condition *regexp2.Regexp, inverted bool, rules []Li
 }
 
 // Apply checks the condition and either runs inner rules or passes the line through.
-func (r *ConditionalLineRule) Apply(line string, ctx *LineContext) ([]string, error) {
+func (r *ConditionalLineRule) Apply(dst []string, line string, ctx *LineContext) ([]string, error) {
 	matches, err := r.condition.MatchString(line)
 	if err != nil {
 		return nil, err
@@ -31,26 +38,29 @@ func (r *ConditionalLineRule) Apply(line string, ctx *LineContext) ([]string, er
 	}
 
 	if !matches {
-		return []string{line}, nil
+		return append(dst, line), nil
 	}
+	ctx.Matched = true
 
-	// Apply inner rules as a pipeline — same pattern as ApplyAllRule
-	current := []string{line}
-	for _, innerRule := range r.rules {
-		var next []string
-		for _, l := range current {
-			out, err := innerRule.Apply(l, ctx)
-			if err != nil {
-				return nil, err
-			}
-			next = append(next, out...)
-		}
-		if len(next) == 0 {
-			return nil, nil
+	// Apply inner rules as a pipeline — same as ApplyAllRule
+	return ApplyRules(dst, r.rules, line, ctx)
+}
+
+func (r *ConditionalLineRule) String() string { return conditionString(r.condition, r.inverted) }
+
+func (r *ConditionalLineRule) Patterns() []Matcher { return []Matcher{r.condition} }
+
+func (r *ConditionalLineRule) Inner() []any { return innerRules(r.rules) }
+
+// Stateless reports whether all inner rules are stateless; the condition
+// itself only looks at the line.
+func (r *ConditionalLineRule) Stateless() bool {
+	for _, inner := range r.rules {
+		if !IsStateless(inner) {
+			return false
 		}
-		current = next
 	}
-	return current, nil
+	return true
 }
 
 // ConditionalDocRule implements DocumentRule. It collects lines matching the
@@ -58,13 +68,13 @@ func (r *ConditionalLineRule) Apply(line string, ctx *LineContext) ([]string, er
 // then weaves the results back into the original positions. Non-matching lines
 // stay in place.
 type ConditionalDocRule struct {
-	condition *regexp2.Regexp
+	condition Matcher
 	inverted  bool
 	rules     []DocumentRule
 }
 
 // NewConditionalDocRule creates a ConditionalDocRule.
-func NewConditionalDocRule(condition *regexp2.Regexp, inverted bool, rules []DocumentRule) *ConditionalDocRule {
+func NewConditionalDocRule(condition Matcher, inverted bool, rules []DocumentRule) *ConditionalDocRule {
 	return &ConditionalDocRule{
 		condition: condition,
 		inverted:  inverted,
@@ -72,16 +82,25 @@ func NewConditionalDocRule(condition *regexp2.Regexp, inverted bool, rules []Doc
 	}
 }
 
+func (r *ConditionalDocRule) String() string { return conditionString(r.condition, r.inverted) }
+
+func (r *ConditionalDocRule) Patterns() []Matcher { return []Matcher{r.condition} }
+
+func (r *ConditionalDocRule) Inner() []any { return innerRules(r.rules) }
+
 // ApplyDocument collects matching lines, applies inner rules, then reconstructs
 // the output with processed lines replacing their original positions.
-func (r *ConditionalDocRule) ApplyDocument(lines []string) ([]string, error) {
+func (r *ConditionalDocRule) ApplyDocument(lines []string, ctx *LineContext) ([]string, error) {
 	var matchingLines []string
 	isMatch := make([]bool, len(lines))
 
 	for i, line := range lines {
+		if err := cancelled(ctx, i+1); err != nil {
+			return nil, err
+		}
 		matches, err := r.condition.MatchString(line)
 		if err != nil {
-			return nil, err
+			return nil, &LineError{Line: i + 1, Rule: r, Err: err}
 		}
 		if r.inverted {
 			matches = !matches
@@ -89,6 +108,7 @@ func (r *ConditionalDocRule) ApplyDocument(lines []string) ([]string, error) {
 		if matches {
 			matchingLines = append(matchingLines, line)
 			isMatch[i] = true
+			ctx.Matched = true
 		}
 	}
 
@@ -96,7 +116,7 @@ func (r *ConditionalDocRule) ApplyDocument(lines []string) ([]string, error) {
 	processed := matchingLines
 	for _, dr := range r.rules {
 		var err error
-		processed, err = dr.ApplyDocument(processed)
+		processed, err = dr.ApplyDocument(processed, ctx)
 		if err != nil {
 			return nil, err
 		}
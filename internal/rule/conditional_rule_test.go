@@ -12,7 +12,7 @@ func TestConditionalLineRule_MatchingLine(t *testing.T) {
 	sub, _ := NewSubstitutionRule("o", "x")
 	cond := NewConditionalLineRule(regexp2.MustCompile("hello", 0), false, []LineRule{sub})
 
-	result, err := cond.Apply("hello world", &LineContext{LineNum: 1})
+	result, err := cond.Apply(nil, "hello world", &LineContext{LineNum: 1})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -25,7 +25,7 @@ func TestConditionalLineRule_NonMatchingLine(t *testing.T) {
 	sub, _ := NewSubstitutionRule("o", "x")
 	cond := NewConditionalLineRule(regexp2.MustCompile("hello", 0), false, []LineRule{sub})
 
-	result, err := cond.Apply("goodbye world", &LineContext{LineNum: 1})
+	result, err := cond.Apply(nil, "goodbye world", &LineContext{LineNum: 1})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -39,7 +39,7 @@ func TestConditionalLineRule_Inverted(t *testing.T) {
 	cond := NewConditionalLineRule(regexp2.MustCompile("hello", 0), true, []LineRule{sub})
 
 	// "hello" matches the pattern, so inverted means rules DON'T apply
-	result, err := cond.Apply("hello world", &LineContext{LineNum: 1})
+	result, err := cond.Apply(nil, "hello world", &LineContext{LineNum: 1})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -49,7 +49,7 @@ func TestConditionalLineRule_Inverted(t *testing.T) {
 
 	// "goodbye" doesn't match, so inverted means rules DO apply
 	// First match only: first "o" in "goodbye" → "x"
-	result, err = cond.Apply("goodbye world", &LineContext{LineNum: 1})
+	result, err = cond.Apply(nil, "goodbye world", &LineContext{LineNum: 1})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -63,7 +63,7 @@ func TestConditionalLineRule_MultipleInnerRules(t *testing.T) {
 	sub2, _ := NewSubstitutionRule("b", "c")
 	cond := NewConditionalLineRule(regexp2.MustCompile("x", 0), false, []LineRule{sub1, sub2})
 
-	result, err := cond.Apply("xab", &LineContext{LineNum: 1})
+	result, err := cond.Apply(nil, "xab", &LineContext{LineNum: 1})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -78,7 +78,7 @@ func TestConditionalLineRule_InnerDeleteRemovesLine(t *testing.T) {
 	del, _ := NewDeleteLineRule("hello")
 	cond := NewConditionalLineRule(regexp2.MustCompile("hello", 0), false, []LineRule{del})
 
-	result, err := cond.Apply("hello world", &LineContext{LineNum: 1})
+	result, err := cond.Apply(nil, "hello world", &LineContext{LineNum: 1})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -92,7 +92,7 @@ func TestConditionalLineRule_PassesLineNum(t *testing.T) {
 	cond := NewConditionalLineRule(regexp2.MustCompile(".*", 0), false, []LineRule{lineNumRule})
 
 	// Line 3 should be kept
-	result, err := cond.Apply("hello", &LineContext{LineNum: 3})
+	result, err := cond.Apply(nil, "hello", &LineContext{LineNum: 3})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -101,7 +101,7 @@ func TestConditionalLineRule_PassesLineNum(t *testing.T) {
 	}
 
 	// Line 2 should be filtered out by PrintLineNumRule
-	result, err = cond.Apply("hello", &LineContext{LineNum: 2})
+	result, err = cond.Apply(nil, "hello", &LineContext{LineNum: 2})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -121,7 +121,7 @@ func TestConditionalDocRule_SortMatchingLines(t *testing.T) {
 	)
 
 	input := []string{"header", "item c", "item a", "footer", "item b"}
-	result, err := cond.ApplyDocument(input)
+	result, err := cond.ApplyDocument(input, &LineContext{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -145,7 +145,7 @@ func TestConditionalDocRule_ReverseMatchingLines(t *testing.T) {
 	)
 
 	input := []string{"a", "x1", "b", "x2", "x3"}
-	result, err := cond.ApplyDocument(input)
+	result, err := cond.ApplyDocument(input, &LineContext{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -171,7 +171,7 @@ func TestConditionalDocRule_JoinMatchingLines(t *testing.T) {
 	)
 
 	input := []string{"header", "item a", "item b", "item c", "footer"}
-	result, err := cond.ApplyDocument(input)
+	result, err := cond.ApplyDocument(input, &LineContext{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -197,7 +197,7 @@ func TestConditionalDocRule_Inverted(t *testing.T) {
 	)
 
 	input := []string{"c", "KEEP", "a", "b"}
-	result, err := cond.ApplyDocument(input)
+	result, err := cond.ApplyDocument(input, &LineContext{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -222,7 +222,8 @@ func TestConditionalDocRule_NoMatches(t *testing.T) {
 	)
 
 	input := []string{"c", "a", "b"}
-	result, err := cond.ApplyDocument(input)
+	ctx := &LineContext{}
+	result, err := cond.ApplyDocument(input, ctx)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -231,6 +232,10 @@ func TestConditionalDocRule_NoMatches(t *testing.T) {
 	if len(result) != 3 || result[0] != "c" || result[1] != "a" || result[2] != "b" {
 		t.Errorf("got %v, want [c a b]", result)
 	}
+	// The inner sort never ran on a non-empty document
+	if ctx.Matched {
+		t.Error("expected Matched=false")
+	}
 }
 
 func TestConditionalDocRule_SubThenSort(t *testing.T) {
@@ -243,7 +248,7 @@ func TestConditionalDocRule_SubThenSort(t *testing.T) {
 	)
 
 	input := []string{"header", "item c", "item a", "footer", "item b"}
-	result, err := cond.ApplyDocument(input)
+	result, err := cond.ApplyDocument(input, &LineContext{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -13,7 +13,7 @@ func processLines(t *testing.T, r LineRule, lines []string) []string {
 	var result []string
 	for i, line := range lines {
 		ctx.LineNum = i + 1
-		out, err := r.Apply(line, ctx)
+		out, err := r.Apply(nil, line, ctx)
 		if err != nil {
 			t.Fatalf("Apply error: %v", err)
 		}
@@ -113,8 +113,8 @@ func TestOnOff_Combined(t *testing.T) {
 	var result []string
 	for i, line := range lines {
 		ctx.LineNum = i + 1
-		on.Apply(line, ctx)
-		off.Apply(line, ctx)
+		on.Apply(nil, line, ctx)
+		off.Apply(nil, line, ctx)
 		if ctx.Printing != PrintOff {
 			result = append(result, line)
 		}
@@ -1,16 +1,18 @@
 package rule
 
-import "github.com/dlclark/regexp2"
-
 // DeleteLineRule removes lines that match a pattern, keeps non-matching lines.
 type DeleteLineRule struct {
 	patternStr string
-	pattern    *regexp2.Regexp
+	pattern    Matcher
 }
 
 // Pattern returns the original pattern string.
 func (r *DeleteLineRule) Pattern() string { return r.patternStr }
 
+func (r *DeleteLineRule) String() string { return "d/" + r.patternStr + "/" }
+
+func (r *DeleteLineRule) Patterns() []Matcher { return []Matcher{r.pattern} }
+
 // NewDeleteLineRule creates a rule that removes lines matching the pattern.
 // Use WithIgnoreCase() for case-insensitive matching.
 func NewDeleteLineRule(patternStr string, opts ...RuleOption) (*DeleteLineRule, error) {
@@ -24,14 +26,18 @@ func NewDeleteLineRule(patternStr string, opts ...RuleOption) (*DeleteLineRule,
 	}, nil
 }
 
-// Apply returns empty slice if line matches, keeps the line if not.
-func (r *DeleteLineRule) Apply(line string, ctx *LineContext) ([]string, error) {
+// Apply drops the line if it matches, keeps it if not.
+func (r *DeleteLineRule) Apply(dst []string, line string, ctx *LineContext) ([]string, error) {
 	matched, err := r.pattern.MatchString(line)
 	if err != nil {
 		return nil, err
 	}
 	if matched {
-		return []string{}, nil // Delete: line matches
+		ctx.Matched = true
+		return dst, nil // Delete: line matches
 	}
-	return []string{line}, nil // Keep: line doesn't match
+	return append(dst, line), nil // Keep: line doesn't match
 }
+
+// Stateless reports true: whether a line is deleted depends only on the line.
+func (r *DeleteLineRule) Stateless() bool { return true }
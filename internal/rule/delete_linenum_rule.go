@@ -1,5 +1,7 @@
 package rule
 
+import "fmt"
+
 // DeleteLineNumRule removes lines that match a line number range, keeps others.
 type DeleteLineNumRule struct {
 	lineRange LineRange
@@ -10,10 +12,13 @@ func NewDeleteLineNumRule(lineRange LineRange) *DeleteLineNumRule {
 	return &DeleteLineNumRule{lineRange: lineRange}
 }
 
-// Apply returns empty slice if line number matches the range, keeps the line if not.
-func (r *DeleteLineNumRule) Apply(line string, ctx *LineContext) ([]string, error) {
+func (r *DeleteLineNumRule) String() string { return fmt.Sprintf("d:%v", r.lineRange) }
+
+// Apply drops the line if its line number matches the range, keeps it if not.
+func (r *DeleteLineNumRule) Apply(dst []string, line string, ctx *LineContext) ([]string, error) {
 	if r.lineRange.Contains(ctx.LineNum) {
-		return []string{}, nil // Delete: line number matches
+		ctx.Matched = true
+		return dst, nil // Delete: line number matches
 	}
-	return []string{line}, nil // Keep: line number doesn't match
+	return append(dst, line), nil // Keep: line number doesn't match
 }
@@ -0,0 +1,62 @@
+package rule
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// LineError is an error from applying a rule, giving the line it happened
+// on. Rule is the innermost rule that failed, or nil if processing was
+// cancelled between rules.
+type LineError struct {
+	Line int // 1-indexed, as in LineContext.LineNum
+	Rule any // a LineRule or DocumentRule
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	if e.Rule == nil {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("line %d: %s: %v", e.Line, Describe(e.Rule), e.Err)
+}
+
+func (e *LineError) Unwrap() error { return e.Err }
+
+// Describe names a rule for messages: its String method if it has one,
+// otherwise its type.
+func Describe(r any) string {
+	if s, ok := r.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%T", r), "*rule.")
+}
+
+// ApplyLine applies r to line, appending its output to dst. An error is
+// wrapped in a LineError naming r and ctx.LineNum, unless it already is one
+// from a rule inside r.
+func ApplyLine(dst []string, r LineRule, line string, ctx *LineContext) ([]string, error) {
+	dst, err := r.Apply(dst, line, ctx)
+	if err != nil {
+		return nil, lineError(ctx.LineNum, r, err)
+	}
+	return dst, nil
+}
+
+// lineError wraps err in a LineError unless it already is one.
+func lineError(line int, r any, err error) error {
+	var lerr *LineError
+	if errors.As(err, &lerr) {
+		return err
+	}
+	return &LineError{Line: line, Rule: r, Err: err}
+}
+
+// cancelled returns a LineError if ctx has been cancelled.
+func cancelled(ctx *LineContext, line int) error {
+	if err := ctx.Err(); err != nil {
+		return &LineError{Line: line, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package rule
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlclark/regexp2"
+)
+
+func TestWithMatchTimeout(t *testing.T) {
+	r, err := NewSubstitutionRule(`(a+)+$`, "x", WithMatchTimeout(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := &LineContext{LineNum: 3}
+	_, err = ApplyLine(nil, r, strings.Repeat("a", 40)+"!", ctx)
+	var lerr *LineError
+	if !errors.As(err, &lerr) {
+		t.Fatalf("expected a LineError, got %v", err)
+	}
+	if lerr.Line != 3 || lerr.Rule != r {
+		t.Errorf("got line %d, rule %v; want line 3, %v", lerr.Line, lerr.Rule, r)
+	}
+	if !strings.HasPrefix(err.Error(), "line 3: s/(a+)+$/x/: ") {
+		t.Errorf("got %q", err.Error())
+	}
+}
+
+func TestApplyLine_KeepsInnerError(t *testing.T) {
+	inner, _ := NewSubstitutionRule(`(a+)+$`, "x", WithMatchTimeout(10*time.Millisecond))
+	outer := NewConditionalLineRule(regexp2.MustCompile("a", 0), false, []LineRule{inner})
+
+	_, err := ApplyLine(nil, outer, strings.Repeat("a", 40)+"!", &LineContext{LineNum: 1})
+	var lerr *LineError
+	if !errors.As(err, &lerr) || lerr.Rule != inner {
+		t.Errorf("expected the error to name the inner rule, got %v", err)
+	}
+}
+
+func TestApplyAllRule_Cancelled(t *testing.T) {
+	sub, _ := NewSubstitutionRule("a", "b")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := NewApplyAllRule([]LineRule{sub}).ApplyDocument([]string{"a", "a"}, &LineContext{Context: ctx})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if err.Error() != "line 1: context canceled" {
+		t.Errorf("got %q", err.Error())
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	sub, _ := NewSubstitutionRule("a", "b", WithGlobal())
+	if got := Describe(sub); got != "s/a/b/g" {
+		t.Errorf("got %q, want %q", got, "s/a/b/g")
+	}
+	if got := Describe(NewSortRule()); got != "SortRule" {
+		t.Errorf("got %q, want %q", got, "SortRule")
+	}
+}
@@ -12,10 +12,18 @@ func NewJoinRule(separator string) *JoinRule {
 	return &JoinRule{separator: separator}
 }
 
+func (r *JoinRule) String() string {
+	if r.separator == "" {
+		return "join"
+	}
+	return "join/" + r.separator + "/"
+}
+
 // ApplyDocument joins all lines with the separator and returns a single-element slice.
-func (r *JoinRule) ApplyDocument(lines []string) ([]string, error) {
+func (r *JoinRule) ApplyDocument(lines []string, ctx *LineContext) ([]string, error) {
 	if len(lines) == 0 {
 		return []string{}, nil
 	}
+	ctx.Matched = true
 	return []string{strings.Join(lines, r.separator)}, nil
 }
@@ -6,7 +6,7 @@ import (
 
 func TestJoinRule_JoinsWithComma(t *testing.T) {
 	r := NewJoinRule(",")
-	result, err := r.ApplyDocument([]string{"a", "b", "c"})
+	result, err := r.ApplyDocument([]string{"a", "b", "c"}, &LineContext{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -18,7 +18,7 @@ func TestJoinRule_JoinsWithComma(t *testing.T) {
 
 func TestJoinRule_JoinsWithSpace(t *testing.T) {
 	r := NewJoinRule(" ")
-	result, err := r.ApplyDocument([]string{"hello", "world"})
+	result, err := r.ApplyDocument([]string{"hello", "world"}, &LineContext{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -30,7 +30,7 @@ func TestJoinRule_JoinsWithSpace(t *testing.T) {
 
 func TestJoinRule_JoinsWithEmptySeparator(t *testing.T) {
 	r := NewJoinRule("")
-	result, err := r.ApplyDocument([]string{"a", "b", "c"})
+	result, err := r.ApplyDocument([]string{"a", "b", "c"}, &LineContext{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -42,7 +42,7 @@ func TestJoinRule_JoinsWithEmptySeparator(t *testing.T) {
 
 func TestJoinRule_EmptyInput(t *testing.T) {
 	r := NewJoinRule(",")
-	result, err := r.ApplyDocument([]string{})
+	result, err := r.ApplyDocument([]string{}, &LineContext{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -54,7 +54,7 @@ func TestJoinRule_EmptyInput(t *testing.T) {
 
 func TestJoinRule_SingleLine(t *testing.T) {
 	r := NewJoinRule(",")
-	result, err := r.ApplyDocument([]string{"only"})
+	result, err := r.ApplyDocument([]string{"only"}, &LineContext{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
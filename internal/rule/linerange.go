@@ -18,6 +18,8 @@ func (s SingleLine) Contains(lineNum int) bool {
 	return int(s) == lineNum
 }
 
+func (s SingleLine) String() string { return strconv.Itoa(int(s)) }
+
 // Range matches a range of line numbers (inclusive).
 type Range struct {
 	Start int
@@ -28,6 +30,8 @@ func (r *Range) Contains(lineNum int) bool {
 	return lineNum >= r.Start && lineNum <= r.End
 }
 
+func (r *Range) String() string { return fmt.Sprintf("%d-%d", r.Start, r.End) }
+
 // OpenRange matches from a start line to the end, or from the beginning to an end line.
 type OpenRange struct {
 	From  int  // starting line (if ToEnd is true)
@@ -42,6 +46,13 @@ func (r *OpenRange) Contains(lineNum int) bool {
 	return lineNum <= r.To
 }
 
+func (r *OpenRange) String() string {
+	if r.ToEnd {
+		return fmt.Sprintf("%d-", r.From)
+	}
+	return fmt.Sprintf("-%d", r.To)
+}
+
 // CompositeRange combines multiple ranges with OR logic.
 type CompositeRange struct {
 	Ranges []LineRange
@@ -56,6 +67,14 @@ func (c *CompositeRange) Contains(lineNum int) bool {
 	return false
 }
 
+func (c *CompositeRange) String() string {
+	parts := make([]string, len(c.Ranges))
+	for i, r := range c.Ranges {
+		parts[i] = fmt.Sprint(r)
+	}
+	return strings.Join(parts, ",")
+}
+
 // ParseLineRange parses a line range specification.
 // Supported formats:
 //   - "5"     - single line
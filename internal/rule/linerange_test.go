@@ -1,6 +1,9 @@
 package rule
 
-import "testing"
+import (
+	"fmt"
+	"testing"
+)
 
 func TestSingleLine_Contains(t *testing.T) {
 	r := SingleLine(5)
@@ -181,3 +184,15 @@ func TestParseLineRange_Invalid(t *testing.T) {
 		t.Error("expected error for empty string")
 	}
 }
+
+func TestParseLineRange_String(t *testing.T) {
+	for _, spec := range []string{"5", "2-4", "5-", "-5", "1,3-4,7-"} {
+		r, err := ParseLineRange(spec)
+		if err != nil {
+			t.Fatalf("ParseLineRange(%q): %v", spec, err)
+		}
+		if got := r.(fmt.Stringer).String(); got != spec {
+			t.Errorf("ParseLineRange(%q).String() = %q", spec, got)
+		}
+	}
+}
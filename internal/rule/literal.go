@@ -0,0 +1,121 @@
+package rule
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// literalMatcher is a Matcher for a pattern that only matches one piece of
+// text, using strings.Index instead of a regex engine.
+type literalMatcher struct {
+	lit    string
+	source string // the pattern, with its escapes
+}
+
+func (m *literalMatcher) MatchString(s string) (bool, error) {
+	return strings.Contains(s, m.lit), nil
+}
+
+func (m *literalMatcher) String() string { return m.source }
+
+func (m *literalMatcher) describe() string { return "literal" }
+
+func (m *literalMatcher) Replace(input, replacement string, startAt, count int) (string, error) {
+	var matches [][]int
+	for i := startAt; i <= len(input) && (count < 0 || len(matches) < count); {
+		j := strings.Index(input[i:], m.lit)
+		if j < 0 {
+			break
+		}
+		start := i + j
+		i = start + len(m.lit)
+		matches = append(matches, []int{start, i})
+	}
+	if len(matches) == 0 {
+		return input, nil
+	}
+	tmpl := parseReplacement(replacement, 0, noGroups)
+	return replaceMatches(input, matches, tmpl, startAt, count), nil
+}
+
+func noGroups(string) int { return -1 }
+
+// prefixMatcher is a Matcher whose matches all start with prefix, so that
+// a line without it never reaches the regex engine.
+type prefixMatcher struct {
+	Matcher
+	prefix string
+}
+
+func (m *prefixMatcher) MatchString(s string) (bool, error) {
+	if !strings.Contains(s, m.prefix) {
+		return false, nil
+	}
+	return m.Matcher.MatchString(s)
+}
+
+func (m *prefixMatcher) describe() string {
+	return fmt.Sprintf("%s, literal prefix %q", DescribePattern(m.Matcher), m.prefix)
+}
+
+func (m *prefixMatcher) Replace(input, replacement string, startAt, count int) (string, error) {
+	if !strings.Contains(input, m.prefix) {
+		return input, nil
+	}
+	return m.Matcher.Replace(input, replacement, startAt, count)
+}
+
+// literalPrefix returns the text every match of pattern starts with, and
+// whether pattern matches only that text. It reads just the syntax that
+// means the same in every dialect: plain characters, and metacharacters,
+// '/', '-' and \t \n \r \f \v escaped with a backslash. Anything else ends
+// the prefix, as does a quantifier, which takes back the character before
+// it. A pattern with '|' after the prefix has no prefix.
+func literalPrefix(pattern string) (string, bool) {
+	var sb strings.Builder
+	i := 0
+	for i < len(pattern) {
+		c, n := utf8.DecodeRuneInString(pattern[i:])
+		switch {
+		case c == '\\':
+			if i+1 == len(pattern) {
+				return sb.String(), false
+			}
+			e := pattern[i+1]
+			if lit, ok := controlEscapes[e]; ok {
+				c = lit
+			} else if strings.IndexByte(escapable, e) >= 0 {
+				c = rune(e)
+			} else {
+				return prefixBefore(sb.String(), pattern[i:]), false
+			}
+			n = 2
+		case strings.ContainsRune(metachars, c):
+			return prefixBefore(sb.String(), pattern[i:]), false
+		}
+		if next := i + n; next < len(pattern) && strings.IndexByte(quantifiers, pattern[next]) >= 0 {
+			return prefixBefore(sb.String(), pattern[i:]), false
+		}
+		sb.WriteRune(c)
+		i += n
+	}
+	return sb.String(), true
+}
+
+// prefixBefore returns prefix, unless the rest of the pattern might be an
+// alternative to it.
+func prefixBefore(prefix, rest string) string {
+	if strings.Contains(rest, "|") {
+		return ""
+	}
+	return prefix
+}
+
+const (
+	metachars   = `\.+*?()|[]{}^$`
+	quantifiers = "?*+{"
+	escapable   = metachars + "/-"
+)
+
+var controlEscapes = map[byte]rune{'t': '\t', 'n': '\n', 'r': '\r', 'f': '\f', 'v': '\v'}
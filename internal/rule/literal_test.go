@@ -0,0 +1,168 @@
+package rule
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dlclark/regexp2"
+)
+
+func TestLiteralPrefix(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		want      string
+		wantWhole bool
+	}{
+		{"ERROR", "ERROR", true},
+		{`foo\.bar\/baz`, "foo.bar/baz", true},
+		{`a\tb`, "a\tb", true},
+		{"héllo wörld", "héllo wörld", true},
+		{`ERROR: \d+`, "ERROR: ", false},
+		{"abc?", "ab", false},
+		{"é+x", "", false},
+		{"ab{2}", "a", false},
+		{"foo|bar", "", false},
+		{"foo.*|bar", "", false},
+		{"^foo", "", false},
+		{`\bfoo`, "", false},
+		{`(a)\1`, "", false},
+		{`a\`, "a", false},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		got, whole := literalPrefix(tt.pattern)
+		if got != tt.want || whole != tt.wantWhole {
+			t.Errorf("literalPrefix(%q) = %q, %v; want %q, %v", tt.pattern, got, whole, tt.want, tt.wantWhole)
+		}
+	}
+}
+
+func TestCompilePattern_FastPaths(t *testing.T) {
+	tests := []struct {
+		pattern string
+		opts    []RuleOption
+		want    string
+	}{
+		{`foo\.bar`, nil, "*rule.literalMatcher"},
+		{`foo\.bar`, []RuleOption{WithDialect(RE2)}, "*rule.literalMatcher"},
+		{`ERROR: \d+`, nil, "*rule.prefixMatcher"},
+		{`ERROR: \d+`, []RuleOption{WithDialect(RE2)}, "*rule.re2Matcher"},
+		{`foo`, []RuleOption{WithIgnoreCase()}, "*rule.regexp2Matcher"},
+		{``, nil, "*rule.regexp2Matcher"},
+	}
+	for _, tt := range tests {
+		m, err := CompilePattern(tt.pattern, tt.opts...)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := fmt.Sprintf("%T", m); got != tt.want {
+			t.Errorf("CompilePattern(%q): got %s, want %s", tt.pattern, got, tt.want)
+		}
+		if m.String() != tt.pattern {
+			t.Errorf("String: got %q, want %q", m.String(), tt.pattern)
+		}
+	}
+}
+
+// TestFastPaths_MatchRegexp2 checks the fast paths against regexp2 itself.
+func TestFastPaths_MatchRegexp2(t *testing.T) {
+	tests := []struct {
+		pattern, replace, input string
+		count                   int
+	}{
+		{"o", "0", "foo boo", -1},
+		{"o", "0", "foo boo", 1},
+		{"oo", "[$&|$`|$'|$$|$1|$+]", "foo boo", -1},
+		{"aa", "b", "aaaaa", -1},
+		{"x", "y", "abc", -1},
+		{"ö", "o", "wörld ö", -1},
+		{`ERROR: \d+`, "<$&>", "ERROR: x ERROR: 42", -1},
+		{`ERROR: \d+`, "<$&>", "WARN: 42", -1},
+	}
+	for _, tt := range tests {
+		fast, err := CompilePattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		slow := regexp2.MustCompile(tt.pattern, regexp2.ECMAScript)
+
+		got, _ := fast.MatchString(tt.input)
+		want, _ := slow.MatchString(tt.input)
+		if got != want {
+			t.Errorf("%q on %q: match %v, want %v", tt.pattern, tt.input, got, want)
+		}
+		gotRep, _ := fast.Replace(tt.input, tt.replace, 0, tt.count)
+		wantRep, _ := slow.Replace(tt.input, tt.replace, 0, tt.count)
+		if gotRep != wantRep {
+			t.Errorf("s/%s/%s/ on %q: got %q, want %q", tt.pattern, tt.replace, tt.input, gotRep, wantRep)
+		}
+	}
+}
+
+// benchLines is a log in which one line in 50 is an error.
+var benchLines = func() []string {
+	lines := make([]string, 1000)
+	for i := range lines {
+		level := "INFO"
+		if i%50 == 0 {
+			level = "ERROR"
+		}
+		lines[i] = fmt.Sprintf("2024-01-02T03:04:%02d %s request %d served in %dms from 10.0.0.%d path=/api/v1/items?id=%d agent=Mozilla/5.0",
+			i%60, level, i, i%300, i%256, i*7)
+	}
+	return lines
+}()
+
+// benchPatterns are matched with the pattern CompilePattern returns, and
+// with plain regexp2 as they were before the fast paths.
+var benchPatterns = []struct{ name, pattern string }{
+	{"literal", "ERROR"},
+	{"prefix", `ERROR request \d+`},
+}
+
+func benchMatchers(b *testing.B, fn func(b *testing.B, m Matcher)) {
+	for _, bp := range benchPatterns {
+		fast, err := CompilePattern(bp.pattern)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(bp.name+"/fast", func(b *testing.B) { fn(b, fast) })
+		b.Run(bp.name+"/regexp2", func(b *testing.B) {
+			fn(b, regexp2.MustCompile(bp.pattern, regexp2.ECMAScript))
+		})
+	}
+}
+
+func BenchmarkPrintLineRule(b *testing.B) {
+	benchMatchers(b, func(b *testing.B, m Matcher) {
+		r := &PrintLineRule{pattern: m}
+		ctx := &LineContext{}
+		var out []string
+		b.ReportAllocs()
+		for b.Loop() {
+			for _, line := range benchLines {
+				var err error
+				if out, err = r.Apply(out[:0], line, ctx); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkSubstitutionRule(b *testing.B) {
+	benchMatchers(b, func(b *testing.B, m Matcher) {
+		r := &SubstitutionRule{pattern: m, replace: "FAILED", global: true}
+		ctx := &LineContext{}
+		var out []string
+		b.ReportAllocs()
+		for b.Loop() {
+			for _, line := range benchLines {
+				var err error
+				if out, err = r.Apply(out[:0], line, ctx); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
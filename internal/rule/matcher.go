@@ -0,0 +1,177 @@
+package rule
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dlclark/regexp2"
+)
+
+// Matcher is a compiled pattern. *regexp2.Regexp implements it, and
+// CompilePattern returns one for each Dialect.
+type Matcher interface {
+	// MatchString reports whether s contains a match.
+	MatchString(s string) (bool, error)
+	// Replace replaces up to count matches (-1 for all) starting at or
+	// after startAt, which regexp2 counts in runes and the other matchers
+	// in bytes; rules always pass 0. The replacement uses regexp2's syntax:
+	// $1 or ${1} for a group, ${name} for a named group, $& for the whole
+	// match, $` and $' for the text before and after it, $+ for the last
+	// group, $_ for the whole input, and $$ for a literal '$'.
+	Replace(input, replacement string, startAt, count int) (string, error)
+	// String returns the source text of the pattern.
+	String() string
+}
+
+// Dialect selects the regex engine and syntax a pattern is compiled with.
+type Dialect int
+
+const (
+	// ECMAScript is regexp2 in ECMAScript mode, the default.
+	ECMAScript Dialect = iota
+	// DotNet is regexp2's native syntax, with .NET features such as
+	// Unicode categories (\p{Lu}) and lookbehind of any length.
+	DotNet
+	// RE2 is Go's regexp package, which matches in time linear in the
+	// input. It has no backreferences or lookaround, and needs no timeout.
+	RE2
+)
+
+var dialectNames = [...]string{
+	ECMAScript: "ecmascript",
+	DotNet:     "dotnet",
+	RE2:        "re2",
+}
+
+func (d Dialect) String() string {
+	if d < 0 || int(d) >= len(dialectNames) {
+		return fmt.Sprintf("Dialect(%d)", int(d))
+	}
+	return dialectNames[d]
+}
+
+// ParseDialect returns the dialect with the given name, as printed by
+// Dialect.String.
+func ParseDialect(name string) (Dialect, error) {
+	for d, n := range dialectNames {
+		if n == name {
+			return Dialect(d), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown regex dialect %q: must be one of %s", name, strings.Join(dialectNames[:], ", "))
+}
+
+// CompilePattern compiles a regex pattern in the configured dialect,
+// ECMAScript by default. WithIgnoreCase and WithMatchTimeout apply to every
+// dialect that supports them; RE2 is linear-time and ignores the timeout.
+//
+// A pattern that is plain text, such as one from a quote delimiter, is
+// matched with strings.Index rather than a regex engine. A regexp2 pattern
+// that starts with plain text skips the engine on lines without that text.
+func CompilePattern(pattern string, opts ...RuleOption) (Matcher, error) {
+	cfg := buildConfig(opts)
+	var prefix string
+	if !cfg.ignoreCase {
+		var whole bool
+		if prefix, whole = literalPrefix(pattern); whole && prefix != "" {
+			return &literalMatcher{lit: prefix, source: pattern}, nil
+		}
+	}
+	// Go's regexp has its own prefix scan.
+	if cfg.dialect == RE2 {
+		return compileRE2(pattern, cfg.ignoreCase)
+	}
+
+	options := regexp2.RegexOptions(regexp2.ECMAScript)
+	if cfg.dialect == DotNet {
+		options = regexp2.None
+	}
+	if cfg.ignoreCase {
+		options |= regexp2.IgnoreCase
+	}
+	re, err := regexp2.Compile(pattern, options)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.matchTimeout > 0 {
+		re.MatchTimeout = cfg.matchTimeout
+	}
+	m := &regexp2Matcher{Regexp: re, dialect: cfg.dialect, ignoreCase: cfg.ignoreCase}
+	if prefix != "" {
+		return &prefixMatcher{Matcher: m, prefix: prefix}, nil
+	}
+	return m, nil
+}
+
+// DescribePattern says how a compiled pattern is matched: with which
+// dialect and flags, or as plain text. For example "ecmascript, ignore
+// case", "re2" or "literal".
+func DescribePattern(m Matcher) string {
+	if d, ok := m.(interface{ describe() string }); ok {
+		return d.describe()
+	}
+	return fmt.Sprintf("%T", m)
+}
+
+// flagString appends the flags a pattern was compiled with to desc.
+func flagString(desc string, ignoreCase bool) string {
+	if ignoreCase {
+		desc += ", ignore case"
+	}
+	return desc
+}
+
+// regexp2Matcher is a Matcher backed by regexp2. It keeps the options the
+// pattern was compiled with, which regexp2 does not expose, to describe it.
+type regexp2Matcher struct {
+	*regexp2.Regexp
+	dialect    Dialect
+	ignoreCase bool
+}
+
+func (m *regexp2Matcher) describe() string {
+	desc := flagString(m.dialect.String(), m.ignoreCase)
+	if m.MatchTimeout != regexp2.DefaultMatchTimeout {
+		desc += fmt.Sprintf(", timeout %v", m.MatchTimeout)
+	}
+	return desc
+}
+
+// re2Matcher is a Matcher backed by Go's regexp package.
+type re2Matcher struct {
+	re         *regexp.Regexp
+	source     string // the pattern as given, without the (?i) prefix
+	ignoreCase bool
+}
+
+func compileRE2(pattern string, ignoreCase bool) (*re2Matcher, error) {
+	expr := pattern
+	if ignoreCase {
+		expr = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &re2Matcher{re: re, source: pattern, ignoreCase: ignoreCase}, nil
+}
+
+func (m *re2Matcher) describe() string { return flagString(RE2.String(), m.ignoreCase) }
+
+func (m *re2Matcher) MatchString(s string) (bool, error) { return m.re.MatchString(s), nil }
+
+func (m *re2Matcher) String() string { return m.source }
+
+func (m *re2Matcher) Replace(input, replacement string, startAt, count int) (string, error) {
+	n := count
+	if startAt > 0 {
+		n = -1 // matches before startAt don't count
+	}
+	matches := m.re.FindAllStringSubmatchIndex(input, n)
+	if len(matches) == 0 {
+		return input, nil
+	}
+	tmpl := parseReplacement(replacement, m.re.NumSubexp(), m.re.SubexpIndex)
+	return replaceMatches(input, matches, tmpl, startAt, count), nil
+}
@@ -0,0 +1,137 @@
+package rule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDialect(t *testing.T) {
+	for _, d := range []Dialect{ECMAScript, DotNet, RE2} {
+		got, err := ParseDialect(d.String())
+		if err != nil || got != d {
+			t.Errorf("ParseDialect(%q) = %v, %v", d.String(), got, err)
+		}
+	}
+	if _, err := ParseDialect("pcre"); err == nil {
+		t.Error("expected error for an unknown dialect")
+	}
+}
+
+func TestCompilePattern_Dialects(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		pattern string
+		line    string
+		wantErr bool
+	}{
+		{ECMAScript, `(a)\1`, "aa", false},
+		{RE2, `(a)\1`, "", true}, // no backreferences
+		{DotNet, `\p{Lu}x`, "Ax", false},
+		{RE2, `\p{Lu}x`, "Ax", false},
+		{DotNet, `(?<=a+)b`, "aab", false}, // lookbehind
+		{RE2, `(?<=a)b`, "", true},
+	}
+	for _, tt := range tests {
+		m, err := CompilePattern(tt.pattern, WithDialect(tt.dialect))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%v %q: expected a compile error", tt.dialect, tt.pattern)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v %q: unexpected error: %v", tt.dialect, tt.pattern, err)
+			continue
+		}
+		if ok, _ := m.MatchString(tt.line); !ok {
+			t.Errorf("%v %q: expected a match on %q", tt.dialect, tt.pattern, tt.line)
+		}
+	}
+}
+
+func TestCompilePattern_RE2IgnoreCase(t *testing.T) {
+	m, err := CompilePattern("abc", WithDialect(RE2), WithIgnoreCase())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok, _ := m.MatchString("xABCx"); !ok {
+		t.Error("expected a case-insensitive match")
+	}
+	if m.String() != "abc" {
+		t.Errorf("String: got %q, want %q", m.String(), "abc")
+	}
+}
+
+// TestRE2Replace checks that replacements mean the same in RE2 as in the
+// default dialect.
+func TestRE2Replace(t *testing.T) {
+	tests := []struct {
+		pattern, replace, input string
+		count                   int
+	}{
+		{`(\w+) (\w+)`, "$2 $1", "hello world", 1},
+		{`(\w+) (\w+)`, "${2}x${1}", "hello world", 1},
+		{`(?<first>\w+) \w+`, "${first}!", "hello world", 1},
+		{`o`, "[$&]", "foo boo", -1},
+		{`o`, "[$&]", "foo boo", 1},
+		{`b`, "<$`|$'>", "abc", 1},
+		{`(a)(b)?`, "$+.", "ac", 1},
+		{`b`, "$_", "abc", 1},
+		{`b`, "$$1 $9 ${x} $", "abc", 1},
+		{`(a)`, "$12", "a", 1}, // group 1, then "2"
+		{`x*`, "-", "abc", -1},
+	}
+	for _, tt := range tests {
+		want, err := mustCompile(t, tt.pattern, ECMAScript).Replace(tt.input, tt.replace, 0, tt.count)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, err := mustCompile(t, tt.pattern, RE2).Replace(tt.input, tt.replace, 0, tt.count)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != want {
+			t.Errorf("s/%s/%s/ on %q: got %q, want %q", tt.pattern, tt.replace, tt.input, got, want)
+		}
+	}
+}
+
+func TestRE2Replace_StartAt(t *testing.T) {
+	got, _ := mustCompile(t, "a", RE2).Replace("aaaa", "b", 2, 1)
+	if got != "aaba" {
+		t.Errorf("got %q, want %q", got, "aaba")
+	}
+}
+
+func mustCompile(t *testing.T, pattern string, d Dialect) Matcher {
+	t.Helper()
+	m, err := CompilePattern(pattern, WithDialect(d))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return m
+}
+
+func TestDescribePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		opts    []RuleOption
+		want    string
+	}{
+		{`foo`, nil, "literal"},
+		{`^foo$`, nil, "ecmascript"},
+		{`foo\d`, nil, `ecmascript, literal prefix "foo"`},
+		{`^foo$`, []RuleOption{WithDialect(DotNet), WithIgnoreCase()}, "dotnet, ignore case"},
+		{`^foo$`, []RuleOption{WithMatchTimeout(time.Second)}, "ecmascript, timeout 1s"},
+		{`^foo$`, []RuleOption{WithDialect(RE2), WithIgnoreCase()}, "re2, ignore case"},
+	}
+	for _, tt := range tests {
+		m, err := CompilePattern(tt.pattern, tt.opts...)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := DescribePattern(m); got != tt.want {
+			t.Errorf("DescribePattern(%q): got %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
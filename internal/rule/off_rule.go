@@ -1,11 +1,9 @@
 package rule
 
-import "github.com/dlclark/regexp2"
-
 // OffRule stops printing when a line matches the pattern.
 // The matching line itself is not printed.
 type OffRule struct {
-	pattern *regexp2.Regexp
+	pattern Matcher
 }
 
 // NewOffRule creates a rule that turns printing off at the first matching line.
@@ -18,6 +16,10 @@ func NewOffRule(patternStr string, opts ...RuleOption) (*OffRule, error) {
 	return &OffRule{pattern: pattern}, nil
 }
 
+func (r *OffRule) String() string { return "off/" + r.pattern.String() + "/" }
+
+func (r *OffRule) Patterns() []Matcher { return []Matcher{r.pattern} }
+
 // Setup initializes the print state to on — lines are printed until a match.
 // Only sets the initial state if no other control rule has set it first.
 func (r *OffRule) Setup(ctx *LineContext) {
@@ -27,13 +29,14 @@ func (r *OffRule) Setup(ctx *LineContext) {
 }
 
 // Apply checks for a pattern match and turns printing off.
-func (r *OffRule) Apply(line string, ctx *LineContext) ([]string, error) {
+func (r *OffRule) Apply(dst []string, line string, ctx *LineContext) ([]string, error) {
 	matched, err := r.pattern.MatchString(line)
 	if err != nil {
 		return nil, err
 	}
 	if matched {
+		ctx.Matched = true
 		ctx.Printing = PrintOff
 	}
-	return []string{line}, nil
+	return append(dst, line), nil
 }
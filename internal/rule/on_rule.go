@@ -1,11 +1,9 @@
 package rule
 
-import "github.com/dlclark/regexp2"
-
 // OnRule starts printing when a line matches the pattern.
 // The matching line itself is printed.
 type OnRule struct {
-	pattern *regexp2.Regexp
+	pattern Matcher
 }
 
 // NewOnRule creates a rule that turns printing on at the first matching line.
@@ -18,6 +16,10 @@ func NewOnRule(patternStr string, opts ...RuleOption) (*OnRule, error) {
 	return &OnRule{pattern: pattern}, nil
 }
 
+func (r *OnRule) String() string { return "on/" + r.pattern.String() + "/" }
+
+func (r *OnRule) Patterns() []Matcher { return []Matcher{r.pattern} }
+
 // Setup initializes the print state to off — lines are suppressed until a match.
 // Only sets the initial state if no other control rule has set it first.
 func (r *OnRule) Setup(ctx *LineContext) {
@@ -27,13 +29,14 @@ func (r *OnRule) Setup(ctx *LineContext) {
 }
 
 // Apply checks for a pattern match and turns printing on.
-func (r *OnRule) Apply(line string, ctx *LineContext) ([]string, error) {
+func (r *OnRule) Apply(dst []string, line string, ctx *LineContext) ([]string, error) {
 	matched, err := r.pattern.MatchString(line)
 	if err != nil {
 		return nil, err
 	}
 	if matched {
+		ctx.Matched = true
 		ctx.Printing = PrintOn
 	}
-	return []string{line}, nil
+	return append(dst, line), nil
 }
@@ -1,16 +1,18 @@
 package rule
 
-import "github.com/dlclark/regexp2"
-
 // PrintLineRule keeps lines that match a pattern, deletes non-matching lines.
 type PrintLineRule struct {
 	patternStr string
-	pattern    *regexp2.Regexp
+	pattern    Matcher
 }
 
 // Pattern returns the original pattern string.
 func (r *PrintLineRule) Pattern() string { return r.patternStr }
 
+func (r *PrintLineRule) String() string { return "p/" + r.patternStr + "/" }
+
+func (r *PrintLineRule) Patterns() []Matcher { return []Matcher{r.pattern} }
+
 // NewPrintLineRule creates a rule that keeps only lines matching the pattern.
 // Use WithIgnoreCase() for case-insensitive matching.
 func NewPrintLineRule(patternStr string, opts ...RuleOption) (*PrintLineRule, error) {
@@ -24,14 +26,18 @@ func NewPrintLineRule(patternStr string, opts ...RuleOption) (*PrintLineRule, er
 	}, nil
 }
 
-// Apply returns the line if it matches, empty slice if not.
-func (r *PrintLineRule) Apply(line string, ctx *LineContext) ([]string, error) {
+// Apply keeps the line if it matches, drops it if not.
+func (r *PrintLineRule) Apply(dst []string, line string, ctx *LineContext) ([]string, error) {
 	matched, err := r.pattern.MatchString(line)
 	if err != nil {
 		return nil, err
 	}
 	if matched {
-		return []string{line}, nil // Keep: line matches
+		ctx.Matched = true
+		return append(dst, line), nil // Keep: line matches
 	}
-	return []string{}, nil // Delete: line doesn't match
+	return dst, nil // Delete: line doesn't match
 }
+
+// Stateless reports true: whether a line is kept depends only on the line.
+func (r *PrintLineRule) Stateless() bool { return true }
@@ -1,5 +1,7 @@
 package rule
 
+import "fmt"
+
 // PrintLineNumRule keeps lines that match a line number range, deletes others.
 type PrintLineNumRule struct {
 	lineRange LineRange
@@ -10,10 +12,13 @@ func NewPrintLineNumRule(lineRange LineRange) *PrintLineNumRule {
 	return &PrintLineNumRule{lineRange: lineRange}
 }
 
-// Apply returns the line if its line number matches the range, empty slice if not.
-func (r *PrintLineNumRule) Apply(line string, ctx *LineContext) ([]string, error) {
+func (r *PrintLineNumRule) String() string { return fmt.Sprintf("p:%v", r.lineRange) }
+
+// Apply keeps the line if its line number matches the range, drops it if not.
+func (r *PrintLineNumRule) Apply(dst []string, line string, ctx *LineContext) ([]string, error) {
 	if r.lineRange.Contains(ctx.LineNum) {
-		return []string{line}, nil // Keep: line number matches
+		ctx.Matched = true
+		return append(dst, line), nil // Keep: line number matches
 	}
-	return []string{}, nil // Delete: line number doesn't match
+	return dst, nil // Delete: line number doesn't match
 }
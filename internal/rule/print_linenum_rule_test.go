@@ -7,19 +7,19 @@ func TestPrintLineNumRule_SingleLine(t *testing.T) {
 	rule := NewPrintLineNumRule(lineRange)
 
 	// Line 1 should be deleted
-	result, _ := rule.Apply("first", &LineContext{LineNum: 1})
+	result, _ := rule.Apply(nil, "first", &LineContext{LineNum: 1})
 	if len(result) != 0 {
 		t.Errorf("line 1 should be deleted, got %v", result)
 	}
 
 	// Line 2 should be kept
-	result, _ = rule.Apply("second", &LineContext{LineNum: 2})
+	result, _ = rule.Apply(nil, "second", &LineContext{LineNum: 2})
 	if len(result) != 1 || result[0] != "second" {
 		t.Errorf("line 2 should be kept, got %v", result)
 	}
 
 	// Line 3 should be deleted
-	result, _ = rule.Apply("third", &LineContext{LineNum: 3})
+	result, _ = rule.Apply(nil, "third", &LineContext{LineNum: 3})
 	if len(result) != 0 {
 		t.Errorf("line 3 should be deleted, got %v", result)
 	}
@@ -30,21 +30,21 @@ func TestPrintLineNumRule_Range(t *testing.T) {
 	rule := NewPrintLineNumRule(lineRange)
 
 	// Line 1 should be deleted
-	result, _ := rule.Apply("one", &LineContext{LineNum: 1})
+	result, _ := rule.Apply(nil, "one", &LineContext{LineNum: 1})
 	if len(result) != 0 {
 		t.Errorf("line 1 should be deleted")
 	}
 
 	// Lines 2, 3, 4 should be kept
 	for i := 2; i <= 4; i++ {
-		result, _ = rule.Apply("content", &LineContext{LineNum: i})
+		result, _ = rule.Apply(nil, "content", &LineContext{LineNum: i})
 		if len(result) != 1 {
-			t.Errorf("line %d should be kept", &LineContext{LineNum: i})
+			t.Errorf("line %d should be kept", i)
 		}
 	}
 
 	// Line 5 should be deleted
-	result, _ = rule.Apply("five", &LineContext{LineNum: 5})
+	result, _ = rule.Apply(nil, "five", &LineContext{LineNum: 5})
 	if len(result) != 0 {
 		t.Errorf("line 5 should be deleted")
 	}
@@ -55,22 +55,22 @@ func TestPrintLineNumRule_OpenRange(t *testing.T) {
 	lineRange, _ := ParseLineRange("3-")
 	rule := NewPrintLineNumRule(lineRange)
 
-	result, _ := rule.Apply("one", &LineContext{LineNum: 1})
+	result, _ := rule.Apply(nil, "one", &LineContext{LineNum: 1})
 	if len(result) != 0 {
 		t.Errorf("line 1 should be deleted")
 	}
 
-	result, _ = rule.Apply("two", &LineContext{LineNum: 2})
+	result, _ = rule.Apply(nil, "two", &LineContext{LineNum: 2})
 	if len(result) != 0 {
 		t.Errorf("line 2 should be deleted")
 	}
 
-	result, _ = rule.Apply("three", &LineContext{LineNum: 3})
+	result, _ = rule.Apply(nil, "three", &LineContext{LineNum: 3})
 	if len(result) != 1 {
 		t.Errorf("line 3 should be kept")
 	}
 
-	result, _ = rule.Apply("hundred", &LineContext{LineNum: 100})
+	result, _ = rule.Apply(nil, "hundred", &LineContext{LineNum: 100})
 	if len(result) != 1 {
 		t.Errorf("line 100 should be kept")
 	}
@@ -0,0 +1,169 @@
+package rule
+
+import "strings"
+
+// replaceMatches replaces up to count of matches (-1 for all) that start at
+// or after startAt with the expansion of tmpl. Each match is a list of
+// submatch indexes, as from regexp.FindAllStringSubmatchIndex.
+func replaceMatches(input string, matches [][]int, tmpl []replacePart, startAt, count int) string {
+	var sb strings.Builder
+	last, done := 0, 0
+	for _, loc := range matches {
+		if loc[0] < startAt {
+			continue
+		}
+		if count >= 0 && done == count {
+			break
+		}
+		sb.WriteString(input[last:loc[0]])
+		for _, part := range tmpl {
+			sb.WriteString(part.expand(input, loc))
+		}
+		last = loc[1]
+		done++
+	}
+	sb.WriteString(input[last:])
+	return sb.String()
+}
+
+// Special references in a replacement, besides group numbers.
+const (
+	refLiteral = -1 - iota // text, not a reference
+	refLeft                // $`
+	refRight               // $'
+	refLast                // $+
+	refInput               // $_
+)
+
+// replacePart is a piece of a parsed replacement: literal text, or a
+// reference to part of the match.
+type replacePart struct {
+	text string
+	ref  int // a group number, or one of the ref constants
+}
+
+func (p replacePart) expand(input string, loc []int) string {
+	switch ref := p.ref; {
+	case ref == refLiteral:
+		return p.text
+	case ref == refLeft:
+		return input[:loc[0]]
+	case ref == refRight:
+		return input[loc[1]:]
+	case ref == refInput:
+		return input
+	case ref == refLast:
+		return submatch(input, loc, len(loc)/2-1)
+	default:
+		return submatch(input, loc, ref)
+	}
+}
+
+// submatch returns the text of group n, or "" if it did not take part.
+func submatch(input string, loc []int, n int) string {
+	if loc[2*n] < 0 {
+		return ""
+	}
+	return input[loc[2*n]:loc[2*n+1]]
+}
+
+// parseReplacement parses regexp2's replacement syntax, described at
+// Matcher.Replace, for a pattern with the given number of groups;
+// groupIndex returns the number of a named group, or -1. As in regexp2, a
+// reference to a group that doesn't exist is literal text, and $12 means
+// group 12 only if there is one, otherwise group 1 followed by "2".
+func parseReplacement(rep string, groups int, groupIndex func(name string) int) []replacePart {
+	var parts []replacePart
+	var lit strings.Builder
+	add := func(ref int) {
+		if lit.Len() > 0 {
+			parts = append(parts, replacePart{text: lit.String(), ref: refLiteral})
+			lit.Reset()
+		}
+		parts = append(parts, replacePart{ref: ref})
+	}
+
+	for i := 0; i < len(rep); i++ {
+		if rep[i] != '$' || i+1 == len(rep) {
+			lit.WriteByte(rep[i])
+			continue
+		}
+		next := rep[i+1]
+		switch {
+		case next == '$':
+			lit.WriteByte('$')
+			i++
+		case next == '&':
+			add(0)
+			i++
+		case next == '`':
+			add(refLeft)
+			i++
+		case next == '\'':
+			add(refRight)
+			i++
+		case next == '+':
+			add(refLast)
+			i++
+		case next == '_':
+			add(refInput)
+			i++
+		case isDigit(next):
+			// The longest run of digits that names a group
+			ref, end := -1, i+1
+			for j, n := i+1, 0; j < len(rep) && isDigit(rep[j]); j++ {
+				n = n*10 + int(rep[j]-'0')
+				if n > groups {
+					break
+				}
+				ref, end = n, j+1
+			}
+			if ref < 0 {
+				lit.WriteByte('$')
+				continue
+			}
+			add(ref)
+			i = end - 1
+		case next == '{':
+			n := strings.IndexByte(rep[i+2:], '}')
+			if n < 0 {
+				lit.WriteByte('$')
+				continue
+			}
+			name := rep[i+2 : i+2+n]
+			ref := groupIndex(name)
+			if num, ok := groupNumber(name); ok && num <= groups {
+				ref = num
+			}
+			if ref < 0 {
+				lit.WriteByte('$')
+				continue
+			}
+			add(ref)
+			i += 2 + n
+		default:
+			lit.WriteByte('$')
+		}
+	}
+	if lit.Len() > 0 {
+		parts = append(parts, replacePart{text: lit.String(), ref: refLiteral})
+	}
+	return parts
+}
+
+// groupNumber parses the digits of ${12}.
+func groupNumber(s string) (int, bool) {
+	if s == "" {
+		return 0, false
+	}
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) || n > 1<<20 {
+			return 0, false
+		}
+		n = n*10 + int(s[i]-'0')
+	}
+	return n, true
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
@@ -11,7 +11,10 @@ func NewReverseRule() *ReverseRule {
 }
 
 // ApplyDocument reverses the line order and returns a new slice.
-func (r *ReverseRule) ApplyDocument(lines []string) ([]string, error) {
+func (r *ReverseRule) ApplyDocument(lines []string, ctx *LineContext) ([]string, error) {
+	if len(lines) > 0 {
+		ctx.Matched = true
+	}
 	reversed := make([]string, len(lines))
 	copy(reversed, lines)
 	slices.Reverse(reversed)
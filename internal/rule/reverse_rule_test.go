@@ -6,7 +6,7 @@ import (
 
 func TestReverseRule_ReversesOrder(t *testing.T) {
 	r := NewReverseRule()
-	result, err := r.ApplyDocument([]string{"a", "b", "c"})
+	result, err := r.ApplyDocument([]string{"a", "b", "c"}, &LineContext{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -24,7 +24,7 @@ func TestReverseRule_ReversesOrder(t *testing.T) {
 
 func TestReverseRule_EmptyInput(t *testing.T) {
 	r := NewReverseRule()
-	result, err := r.ApplyDocument([]string{})
+	result, err := r.ApplyDocument([]string{}, &LineContext{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -36,7 +36,7 @@ func TestReverseRule_EmptyInput(t *testing.T) {
 
 func TestReverseRule_SingleLine(t *testing.T) {
 	r := NewReverseRule()
-	result, err := r.ApplyDocument([]string{"only"})
+	result, err := r.ApplyDocument([]string{"only"}, &LineContext{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -49,7 +49,7 @@ func TestReverseRule_SingleLine(t *testing.T) {
 func TestReverseRule_DoesNotMutateInput(t *testing.T) {
 	r := NewReverseRule()
 	input := []string{"a", "b", "c"}
-	_, _ = r.ApplyDocument(input)
+	_, _ = r.ApplyDocument(input, &LineContext{})
 
 	if input[0] != "a" || input[1] != "b" || input[2] != "c" {
 		t.Errorf("input was mutated: %v", input)
@@ -1,7 +1,11 @@
 // Package rule defines the Rule interfaces and implementations for text transformation.
 package rule
 
-import "github.com/dlclark/regexp2"
+import (
+	"context"
+	"strings"
+	"time"
+)
 
 // PrintState controls whether lines are included in output.
 type PrintState int
@@ -16,10 +20,55 @@ const (
 // Rules that need per-document mutable state store it here via GetState/SetState
 // rather than on the rule struct, so a single rule pipeline can be shared across
 // multiple documents processed in parallel.
+//
+// Matched records whether any rule matched while processing the document:
+// a pattern matched, a line number was in range, or a document rule acted on
+// a non-empty document. Rules only ever set it to true.
+//
+// RecordSep is the separator between records when input is not split into
+// lines (e.g. "\x00" for NUL-delimited input). Rules that produce text
+// containing it, such as a substitution inserting "\n" into a line, emit
+// several records. The zero value means "\n".
+//
+// Context, if set, can cancel processing. The engine checks it before each
+// line, and document rules check it as they work through a document; see
+// Err. A LineContext belongs to one document, so the usual advice against
+// storing a context.Context in a struct doesn't apply.
 type LineContext struct {
-	LineNum  int
-	Printing PrintState
-	state    map[any]any // rule-local state, lazily initialized
+	LineNum   int
+	Printing  PrintState
+	Matched   bool
+	RecordSep string
+	Context   context.Context
+	state     map[any]any // rule-local state, lazily initialized
+	scratch   []*lineBuf  // buffers for ApplyRules, reused from line to line
+	depth     int         // number of scratch buffers in use
+}
+
+// Err returns the error of ctx.Context once it is done, and nil before
+// then or if it is not set.
+func (ctx *LineContext) Err() error {
+	if ctx.Context == nil {
+		return nil
+	}
+	return ctx.Context.Err()
+}
+
+// AppendRecords splits text produced by a rule into records on
+// ctx.RecordSep and appends them to dst.
+func (ctx *LineContext) AppendRecords(dst []string, text string) []string {
+	sep := ctx.RecordSep
+	if sep == "" {
+		sep = "\n"
+	}
+	for {
+		i := strings.Index(text, sep)
+		if i < 0 {
+			return append(dst, text)
+		}
+		dst = append(dst, text[:i])
+		text = text[i+len(sep):]
+	}
 }
 
 // GetState retrieves rule-local state from the context.
@@ -45,12 +94,14 @@ func SetState(ctx *LineContext, key any, val any) {
 }
 
 // LineRule is the core interface for per-line text transformation.
-// Apply takes a line of text and a context (carrying line number and shared state)
-// and returns:
-//   - []string with transformed line(s) - could be 0, 1, or many lines
-//   - error if something goes wrong
+// Apply takes a line of text and a context (carrying line number and shared
+// state), appends the transformed line(s) to dst - could be 0, 1, or many
+// lines - and returns the extended slice, as append does. Apply must not
+// read or keep what dst already holds. Because callers reuse dst, a rule
+// that passes one line through allocates nothing. To chain rules, use
+// ApplyRules.
 type LineRule interface {
-	Apply(line string, ctx *LineContext) ([]string, error)
+	Apply(dst []string, line string, ctx *LineContext) ([]string, error)
 }
 
 // SetupRule is an optional interface for rules that need to initialize
@@ -61,19 +112,63 @@ type SetupRule interface {
 	Setup(ctx *LineContext)
 }
 
+// StatelessRule is an optional interface for LineRules whose output for a
+// line depends on nothing but that line: they keep no state on the
+// LineContext, don't read LineNum and don't change Printing. Lines passing
+// through only stateless rules can be processed out of order, so the engine
+// may split a large input into chunks and process them in parallel.
+// Stateless reports false for a wrapper whose inner rules are not all
+// stateless. Rules that don't implement the interface are order-dependent.
+type StatelessRule interface {
+	Stateless() bool
+}
+
+// IsStateless reports whether r implements StatelessRule and is stateless.
+func IsStateless(r LineRule) bool {
+	s, ok := r.(StatelessRule)
+	return ok && s.Stateless()
+}
+
+// GroupRule is an optional interface for rules that apply other rules to
+// the lines they select, such as ApplyAllRule and if and between blocks.
+// Inner returns those rules, each a LineRule or a DocumentRule, in order.
+type GroupRule interface {
+	Inner() []any
+}
+
+// PatternRule is an optional interface for rules that match lines against
+// patterns. Patterns returns them in the order they are written.
+type PatternRule interface {
+	Patterns() []Matcher
+}
+
+// innerRules returns rules as a []any, for Inner methods.
+func innerRules[R any](rules []R) []any {
+	inner := make([]any, len(rules))
+	for i, r := range rules {
+		inner[i] = r
+	}
+	return inner
+}
+
 // DocumentRule operates on all lines at once.
 // ApplyDocument takes the entire document as a slice of lines and returns
-// the transformed document.
+// the transformed document. ctx is the document's context; document rules
+// use it to report Matched, and pass it on to any inner DocumentRules.
+// Rules that go through the document line by line return a LineError when
+// ctx is cancelled (see LineContext.Err) or a line fails.
 type DocumentRule interface {
-	ApplyDocument(lines []string) ([]string, error)
+	ApplyDocument(lines []string, ctx *LineContext) ([]string, error)
 }
 
 // --- Shared rule options and pattern compilation ---
 
 // ruleConfig holds parsed option state used during rule construction.
 type ruleConfig struct {
-	ignoreCase bool
-	global     bool
+	ignoreCase   bool
+	global       bool
+	matchTimeout time.Duration
+	dialect      Dialect
 }
 
 // RuleOption configures rule behavior. Shared across all regex-based rules.
@@ -95,6 +190,23 @@ func WithGlobal() RuleOption {
 	}
 }
 
+// WithMatchTimeout limits how long a single match of the rule's patterns
+// may run. regexp2 backtracks, so a pattern like (a+)+$ can take
+// exponential time on some lines; a match that runs out of time fails
+// with an error. Zero means no limit.
+func WithMatchTimeout(d time.Duration) RuleOption {
+	return func(c *ruleConfig) {
+		c.matchTimeout = d
+	}
+}
+
+// WithDialect selects the regex dialect patterns are compiled in.
+func WithDialect(d Dialect) RuleOption {
+	return func(c *ruleConfig) {
+		c.dialect = d
+	}
+}
+
 // buildConfig applies options and returns the resolved config.
 func buildConfig(opts []RuleOption) ruleConfig {
 	var cfg ruleConfig
@@ -103,14 +215,3 @@ func buildConfig(opts []RuleOption) ruleConfig {
 	}
 	return cfg
 }
-
-// CompilePattern compiles a regex pattern with ECMAScript mode enabled by default.
-// If the config includes ignoreCase, IgnoreCase is ORed into the options.
-func CompilePattern(pattern string, opts ...RuleOption) (*regexp2.Regexp, error) {
-	cfg := buildConfig(opts)
-	options := regexp2.RegexOptions(regexp2.ECMAScript)
-	if cfg.ignoreCase {
-		options |= regexp2.IgnoreCase
-	}
-	return regexp2.Compile(pattern, options)
-}
@@ -11,7 +11,10 @@ func NewSortRule() *SortRule {
 }
 
 // ApplyDocument sorts the lines alphabetically and returns a new slice.
-func (r *SortRule) ApplyDocument(lines []string) ([]string, error) {
+func (r *SortRule) ApplyDocument(lines []string, ctx *LineContext) ([]string, error) {
+	if len(lines) > 0 {
+		ctx.Matched = true
+	}
 	sorted := make([]string, len(lines))
 	copy(sorted, lines)
 	sort.Strings(sorted)
@@ -6,7 +6,7 @@ import (
 
 func TestSortRule_SortsAlphabetically(t *testing.T) {
 	r := NewSortRule()
-	result, err := r.ApplyDocument([]string{"c", "a", "b"})
+	result, err := r.ApplyDocument([]string{"c", "a", "b"}, &LineContext{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -24,7 +24,7 @@ func TestSortRule_SortsAlphabetically(t *testing.T) {
 
 func TestSortRule_EmptyInput(t *testing.T) {
 	r := NewSortRule()
-	result, err := r.ApplyDocument([]string{})
+	result, err := r.ApplyDocument([]string{}, &LineContext{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -36,7 +36,7 @@ func TestSortRule_EmptyInput(t *testing.T) {
 
 func TestSortRule_SingleLine(t *testing.T) {
 	r := NewSortRule()
-	result, err := r.ApplyDocument([]string{"only"})
+	result, err := r.ApplyDocument([]string{"only"}, &LineContext{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -49,7 +49,7 @@ func TestSortRule_SingleLine(t *testing.T) {
 func TestSortRule_DoesNotMutateInput(t *testing.T) {
 	r := NewSortRule()
 	input := []string{"c", "a", "b"}
-	_, _ = r.ApplyDocument(input)
+	_, _ = r.ApplyDocument(input, &LineContext{})
 
 	// Original should be unchanged
 	if input[0] != "c" || input[1] != "a" || input[2] != "b" {
@@ -1,15 +1,9 @@
 package rule
 
-import (
-	"strings"
-
-	"github.com/dlclark/regexp2"
-)
-
 // SubstitutionRule replaces text matching a pattern.
 type SubstitutionRule struct {
-	patternStr string          // original pattern string
-	pattern    *regexp2.Regexp // compiled regex
+	patternStr string  // original pattern string
+	pattern    Matcher // compiled regex
 	replace    string
 	global     bool
 }
@@ -23,6 +17,16 @@ func (r *SubstitutionRule) Replace() string { return r.replace }
 // Global returns whether all matches are replaced.
 func (r *SubstitutionRule) Global() bool { return r.global }
 
+func (r *SubstitutionRule) String() string {
+	s := "s/" + r.patternStr + "/" + r.replace + "/"
+	if r.global {
+		s += "g"
+	}
+	return s
+}
+
+func (r *SubstitutionRule) Patterns() []Matcher { return []Matcher{r.pattern} }
+
 // NewSubstitutionRule creates a rule that replaces pattern matches with replacement text.
 // By default, only the first match is replaced. Use WithGlobal() to replace all matches.
 // Use WithIgnoreCase() for case-insensitive matching.
@@ -42,7 +46,21 @@ func NewSubstitutionRule(patternStr, replace string, opts ...RuleOption) (*Subst
 }
 
 // Apply performs the substitution on the given line.
-func (r *SubstitutionRule) Apply(line string, ctx *LineContext) ([]string, error) {
+// Until the first match in a document, the pattern is checked with a plain
+// match so that ctx.Matched is accurate even when the replacement leaves the
+// line unchanged. This also skips the replace for lines that can't match.
+func (r *SubstitutionRule) Apply(dst []string, line string, ctx *LineContext) ([]string, error) {
+	if !ctx.Matched {
+		matched, err := r.pattern.MatchString(line)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			return append(dst, line), nil
+		}
+		ctx.Matched = true
+	}
+
 	count := 1
 	if r.global {
 		count = -1 // -1 means replace all
@@ -52,5 +70,8 @@ func (r *SubstitutionRule) Apply(line string, ctx *LineContext) ([]string, error
 		return nil, err
 	}
 
-	return strings.Split(result, "\n"), nil
+	return ctx.AppendRecords(dst, result), nil
 }
+
+// Stateless reports true: a substitution only looks at the line itself.
+func (r *SubstitutionRule) Stateless() bool { return true }
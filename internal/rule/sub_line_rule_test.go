@@ -8,7 +8,7 @@ func TestSubstitutionRule_ReplacesFirstMatch(t *testing.T) {
 		t.Fatalf("failed to create rule: %v", err)
 	}
 
-	result, err := rule.Apply("hello world world", &LineContext{LineNum: 1})
+	result, err := rule.Apply(nil, "hello world world", &LineContext{LineNum: 1})
 	if err != nil {
 		t.Fatalf("Apply failed: %v", err)
 	}
@@ -29,7 +29,7 @@ func TestSubstitutionRule_GlobalReplacesAll(t *testing.T) {
 		t.Fatalf("failed to create rule: %v", err)
 	}
 
-	result, err := rule.Apply("hello world", &LineContext{LineNum: 1})
+	result, err := rule.Apply(nil, "hello world", &LineContext{LineNum: 1})
 	if err != nil {
 		t.Fatalf("Apply failed: %v", err)
 	}
@@ -46,7 +46,7 @@ func TestSubstitutionRule_NoMatch(t *testing.T) {
 		t.Fatalf("failed to create rule: %v", err)
 	}
 
-	result, err := rule.Apply("hello world", &LineContext{LineNum: 1})
+	result, err := rule.Apply(nil, "hello world", &LineContext{LineNum: 1})
 	if err != nil {
 		t.Fatalf("Apply failed: %v", err)
 	}
@@ -63,7 +63,7 @@ func TestSubstitutionRule_RegexPattern(t *testing.T) {
 		t.Fatalf("failed to create rule: %v", err)
 	}
 
-	result, err := rule.Apply("foo 123 bar 456", &LineContext{LineNum: 1})
+	result, err := rule.Apply(nil, "foo 123 bar 456", &LineContext{LineNum: 1})
 	if err != nil {
 		t.Fatalf("Apply failed: %v", err)
 	}
@@ -80,7 +80,7 @@ func TestSubstitutionRule_RegexReplacePattern_WithGlobal(t *testing.T) {
 		t.Fatalf("failed to create rule: %v", err)
 	}
 
-	result, err := rule.Apply("foo 123 bar 456", &LineContext{LineNum: 1})
+	result, err := rule.Apply(nil, "foo 123 bar 456", &LineContext{LineNum: 1})
 	if err != nil {
 		t.Fatalf("Apply failed: %v", err)
 	}
@@ -97,7 +97,7 @@ func TestSubstitutionRule_RegexReplacePattern_OnlyFirst(t *testing.T) {
 		t.Fatalf("failed to create rule: %v", err)
 	}
 
-	result, err := rule.Apply("foo 123 bar 456", &LineContext{LineNum: 1})
+	result, err := rule.Apply(nil, "foo 123 bar 456", &LineContext{LineNum: 1})
 	if err != nil {
 		t.Fatalf("Apply failed: %v", err)
 	}
@@ -116,13 +116,13 @@ func TestSubstitutionRule_LiteralPattern(t *testing.T) {
 	}
 
 	// Should match literal "foo.bar"
-	result, _ := rule.Apply("foo.bar", &LineContext{LineNum: 1})
+	result, _ := rule.Apply(nil, "foo.bar", &LineContext{LineNum: 1})
 	if result[0] != "baz" {
 		t.Errorf("got %q, want %q", result[0], "baz")
 	}
 
 	// Should NOT match "fooXbar"
-	result, _ = rule.Apply("fooXbar", &LineContext{LineNum: 1})
+	result, _ = rule.Apply(nil, "fooXbar", &LineContext{LineNum: 1})
 	if result[0] != "fooXbar" {
 		t.Errorf("got %q, want %q", result[0], "fooXbar")
 	}
@@ -134,7 +134,7 @@ func TestSubstitutionRule_NewlineInReplacement(t *testing.T) {
 		t.Fatalf("failed to create rule: %v", err)
 	}
 
-	result, _ := rule.Apply("foo", &LineContext{LineNum: 1})
+	result, _ := rule.Apply(nil, "foo", &LineContext{LineNum: 1})
 	if len(result) != 2 {
 		t.Fatalf("expected 2 lines, got %d: %q", len(result), result)
 	}
@@ -152,3 +152,58 @@ func TestSubstitutionRule_InvalidRegex(t *testing.T) {
 		t.Error("expected error for invalid regex, got nil")
 	}
 }
+
+func TestSubstitutionRule_SetsMatched(t *testing.T) {
+	rule, err := NewSubstitutionRule("foo", "foo")
+	if err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+
+	ctx := &LineContext{LineNum: 1}
+	if _, err := rule.Apply(nil, "no match here", ctx); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if ctx.Matched {
+		t.Error("expected Matched=false after non-matching line")
+	}
+
+	// Replacing with the same text still counts as a match
+	result, err := rule.Apply(nil, "foo", ctx)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if !ctx.Matched {
+		t.Error("expected Matched=true after matching line")
+	}
+	if result[0] != "foo" {
+		t.Errorf("got %q, want %q", result[0], "foo")
+	}
+}
+
+func TestSubstitutionRule_SplitsOnRecordSep(t *testing.T) {
+	rule, err := NewSubstitutionRule("-", "\n")
+	if err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+
+	// With NUL-separated records, a newline is ordinary content
+	result, err := rule.Apply(nil, "a-b", &LineContext{LineNum: 1, RecordSep: "\x00"})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if len(result) != 1 || result[0] != "a\nb" {
+		t.Errorf("got %q, want [\"a\\nb\"]", result)
+	}
+
+	rule, err = NewSubstitutionRule("-", "\x00")
+	if err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+	result, err = rule.Apply(nil, "a-b", &LineContext{LineNum: 1, RecordSep: "\x00"})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if len(result) != 2 || result[0] != "a" || result[1] != "b" {
+		t.Errorf("got %q, want [a b]", result)
+	}
+}
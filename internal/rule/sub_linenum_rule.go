@@ -1,6 +1,6 @@
 package rule
 
-import "strings"
+import "fmt"
 
 // SubLineNumRule replaces the entire content of lines matching a line number range.
 type SubLineNumRule struct {
@@ -13,10 +13,13 @@ func NewSubLineNumRule(lineRange LineRange, replacement string) *SubLineNumRule
 	return &SubLineNumRule{lineRange: lineRange, replacement: replacement}
 }
 
-// Apply returns the replacement if line number matches, keeps the original line if not.
-func (r *SubLineNumRule) Apply(line string, ctx *LineContext) ([]string, error) {
+func (r *SubLineNumRule) String() string { return fmt.Sprintf("s:%v:%s", r.lineRange, r.replacement) }
+
+// Apply emits the replacement if line number matches, keeps the original line if not.
+func (r *SubLineNumRule) Apply(dst []string, line string, ctx *LineContext) ([]string, error) {
 	if r.lineRange.Contains(ctx.LineNum) {
-		return strings.Split(r.replacement, "\n"), nil
+		ctx.Matched = true
+		return ctx.AppendRecords(dst, r.replacement), nil
 	}
-	return []string{line}, nil
+	return append(dst, line), nil
 }
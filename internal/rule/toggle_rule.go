@@ -1,10 +1,8 @@
 package rule
 
-import "github.com/dlclark/regexp2"
-
 // ToggleRule flips the print state each time a line matches the pattern.
 type ToggleRule struct {
-	pattern *regexp2.Regexp
+	pattern Matcher
 }
 
 // NewToggleRule creates a rule that toggles printing on each matching line.
@@ -17,6 +15,10 @@ func NewToggleRule(patternStr string, opts ...RuleOption) (*ToggleRule, error) {
 	return &ToggleRule{pattern: pattern}, nil
 }
 
+func (r *ToggleRule) String() string { return "toggle/" + r.pattern.String() + "/" }
+
+func (r *ToggleRule) Patterns() []Matcher { return []Matcher{r.pattern} }
+
 // Setup initializes the print state to off.
 // Only sets the initial state if no other control rule has set it first.
 func (r *ToggleRule) Setup(ctx *LineContext) {
@@ -26,17 +28,18 @@ func (r *ToggleRule) Setup(ctx *LineContext) {
 }
 
 // Apply flips the print state when the line matches.
-func (r *ToggleRule) Apply(line string, ctx *LineContext) ([]string, error) {
+func (r *ToggleRule) Apply(dst []string, line string, ctx *LineContext) ([]string, error) {
 	matched, err := r.pattern.MatchString(line)
 	if err != nil {
 		return nil, err
 	}
 	if matched {
+		ctx.Matched = true
 		if ctx.Printing == PrintOn {
 			ctx.Printing = PrintOff
 		} else {
 			ctx.Printing = PrintOn
 		}
 	}
-	return []string{line}, nil
+	return append(dst, line), nil
 }
@@ -0,0 +1,65 @@
+package walk
+
+import (
+	"path"
+	"strings"
+)
+
+// Match reports whether a slash-separated path matches a glob pattern.
+// Segments are matched with path.Match, and a "**" segment matches any
+// number of segments, including none: "vendor/**" matches "vendor" and
+// everything below it, and "**/testdata" matches a testdata directory at
+// any depth. A pattern with no slash matches the path's last segment, so
+// "*.go" matches Go files in every directory. A leading slash anchors a
+// pattern that would otherwise have none.
+func Match(pattern, name string) bool {
+	return matchSegments(splitPattern(pattern), strings.Split(name, "/"))
+}
+
+// ValidatePattern returns an error if pattern is malformed.
+func ValidatePattern(pattern string) error {
+	for _, seg := range splitPattern(pattern) {
+		if seg == "**" {
+			continue
+		}
+		if _, err := path.Match(seg, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitPattern splits a pattern into segments, making an unanchored
+// pattern match at any depth.
+func splitPattern(pattern string) []string {
+	if strings.HasPrefix(pattern, "/") {
+		return strings.Split(pattern[1:], "/")
+	}
+	if !strings.Contains(pattern, "/") {
+		return []string{"**", pattern}
+	}
+	return strings.Split(pattern, "/")
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Try every split point, from consuming nothing to consuming
+			// the rest of the name.
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
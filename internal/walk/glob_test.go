@@ -0,0 +1,42 @@
+package walk
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/ged/main.go", true},
+		{"*.go", "main.go.orig", false},
+		{"cmd/*.go", "cmd/main.go", true},
+		{"cmd/*.go", "cmd/ged/main.go", false},
+		{"cmd/*.go", "x/cmd/main.go", false},
+		{"vendor/**", "vendor", true},
+		{"vendor/**", "vendor/a/b.go", true},
+		{"vendor/**", "src/vendor/a.go", false},
+		{"**/testdata", "a/b/testdata", true},
+		{"**/testdata", "testdata", true},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"/main.go", "main.go", true},
+		{"/main.go", "cmd/main.go", false},
+		{"[ab].txt", "dir/b.txt", true},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidatePattern(t *testing.T) {
+	if err := ValidatePattern("src/**/*.go"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidatePattern("[a"); err == nil {
+		t.Error("expected error for unterminated class")
+	}
+}
@@ -0,0 +1,144 @@
+package walk
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreFile holds the rules of one .gitignore file.
+type ignoreFile struct {
+	dir   string // directory of the file, relative to the walk root ("" for the root)
+	up    string // for a file above the walk root, the root relative to the file's directory
+	rules []ignoreRule
+}
+
+// ignoreRule is one pattern line of a .gitignore file.
+type ignoreRule struct {
+	pattern string // glob, relative to the file's directory
+	negate  bool   // a "!pattern" line re-includes what earlier lines excluded
+	dirOnly bool   // a "pattern/" line matches only directories
+}
+
+// readIgnoreFile parses the .gitignore in dir, if there is one.
+// rel is dir relative to the walk root.
+func readIgnoreFile(dir, rel string) (*ignoreFile, error) {
+	f, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ig := &ignoreFile{dir: rel}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if r, ok := parseIgnoreLine(scanner.Text()); ok {
+			ig.rules = append(ig.rules, r)
+		}
+	}
+	return ig, scanner.Err()
+}
+
+// parentIgnoreFiles returns the .gitignore files that apply to root from
+// above it: those in the directories from the root of the git repository
+// containing root down to root's parent, in that order. If root is not
+// inside a repository, or is the top of one, there are none.
+func parentIgnoreFiles(root string) ([]*ignoreFile, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string // root's ancestors, nearest first
+	for dir := abs; ; {
+		if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil // not in a repository
+		}
+		dir = parent
+		dirs = append(dirs, dir)
+	}
+
+	var files []*ignoreFile
+	for i := len(dirs) - 1; i >= 0; i-- {
+		ig, err := readIgnoreFile(dirs[i], "")
+		if err != nil {
+			return nil, err
+		}
+		if ig == nil {
+			continue
+		}
+		up, err := filepath.Rel(dirs[i], abs)
+		if err != nil {
+			return nil, err
+		}
+		ig.up = filepath.ToSlash(up)
+		files = append(files, ig)
+	}
+	return files, nil
+}
+
+// parseIgnoreLine parses a .gitignore line, reporting false for blank
+// lines and comments. Trailing spaces are dropped unless escaped, and a
+// leading "\#" or "\!" stands for a literal character.
+func parseIgnoreLine(line string) (ignoreRule, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	var r ignoreRule
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+	r.pattern = line
+	return r, true
+}
+
+// ignored reports whether .gitignore files exclude the path rel (relative
+// to the walk root). Only files in rel's ancestors apply, including those
+// above the walk root. Files are ordered
+// from the root down, and as in git the last matching rule wins.
+func ignored(files []*ignoreFile, rel string, isDir bool) bool {
+	ignore := false
+	for _, ig := range files {
+		name := rel
+		switch {
+		case ig.up != "":
+			name = ig.up + "/" + rel
+		case ig.dir != "":
+			var ok bool
+			if name, ok = strings.CutPrefix(rel, ig.dir+"/"); !ok {
+				continue
+			}
+		}
+		for _, r := range ig.rules {
+			if r.dirOnly && !isDir {
+				continue
+			}
+			if Match(r.pattern, name) {
+				ignore = !r.negate
+			}
+		}
+	}
+	return ignore
+}
@@ -0,0 +1,53 @@
+package walk
+
+import "testing"
+
+func TestParseIgnoreLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want ignoreRule
+		ok   bool
+	}{
+		{"", ignoreRule{}, false},
+		{"# comment", ignoreRule{}, false},
+		{"*.log", ignoreRule{pattern: "*.log"}, true},
+		{"*.log  ", ignoreRule{pattern: "*.log"}, true},
+		{"build/", ignoreRule{pattern: "build", dirOnly: true}, true},
+		{"!keep.log", ignoreRule{pattern: "keep.log", negate: true}, true},
+		{`\#file`, ignoreRule{pattern: "#file"}, true},
+	}
+	for _, tt := range tests {
+		got, ok := parseIgnoreLine(tt.line)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseIgnoreLine(%q) = %+v, %v; want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestIgnored(t *testing.T) {
+	files := []*ignoreFile{
+		{dir: "", rules: []ignoreRule{{pattern: "*.log"}, {pattern: "keep.log", negate: true}, {pattern: "build", dirOnly: true}}},
+		{dir: "sub", rules: []ignoreRule{{pattern: "/local.txt"}, {pattern: "keep.log"}}},
+	}
+	tests := []struct {
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{"a.log", false, true},
+		{"keep.log", false, false},
+		{"x/keep.log", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"sub/local.txt", false, true},
+		{"sub/x/local.txt", false, false},
+		{"local.txt", false, false},
+		// The deeper file overrides the root's negation
+		{"sub/keep.log", false, true},
+	}
+	for _, tt := range tests {
+		if got := ignored(files, tt.rel, tt.isDir); got != tt.want {
+			t.Errorf("ignored(%q, dir=%v) = %v, want %v", tt.rel, tt.isDir, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Package walk lists the files below a directory for recursive processing,
+// honoring include and exclude globs and .gitignore files.
+package walk
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Options controls which files Files returns.
+type Options struct {
+	Include  []string // if set, only files matching one of these globs are listed
+	Exclude  []string // files and directories matching any of these globs are skipped
+	Hidden   bool     // descend into directories whose names start with "."
+	NoIgnore bool     // don't read .gitignore files
+}
+
+// Files returns the regular files below root, in lexical order, as paths
+// starting with root. Globs (see Match) are matched against paths relative
+// to root. Directories excluded by a glob, a .gitignore file or for being
+// hidden are not descended into. Symlinks to directories are not followed.
+//
+// The .gitignore files that apply are those below root and, when root is
+// inside a git repository, those between the repository's top and root.
+// A .git directory is never entered, even with Hidden, unless it is root.
+func Files(root string, opts Options) ([]string, error) {
+	for _, patterns := range [][]string{opts.Include, opts.Exclude} {
+		for _, pattern := range patterns {
+			if err := ValidatePattern(pattern); err != nil {
+				return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+			}
+		}
+	}
+
+	w := &walker{opts: opts}
+	var ignores []*ignoreFile
+	if !opts.NoIgnore {
+		var err error
+		if ignores, err = parentIgnoreFiles(root); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.walk(root, "", ignores); err != nil {
+		return nil, err
+	}
+	return w.files, nil
+}
+
+type walker struct {
+	opts  Options
+	files []string
+}
+
+// walk lists dir, whose path relative to the root is rel, and recurses
+// into its subdirectories. ignores are the .gitignore files of dir's
+// ancestors.
+func (w *walker) walk(dir, rel string, ignores []*ignoreFile) error {
+	if !w.opts.NoIgnore {
+		ig, err := readIgnoreFile(dir, rel)
+		if err != nil {
+			return err
+		}
+		if ig != nil {
+			ignores = append(ignores[:len(ignores):len(ignores)], ig)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		full := filepath.Join(dir, entry.Name())
+		entryRel := path.Join(rel, entry.Name())
+
+		isDir := entry.IsDir()
+		if entry.Type()&os.ModeSymlink != 0 {
+			// A symlink to a file is listed like the file; one to a
+			// directory is skipped, which also avoids cycles.
+			info, err := os.Stat(full)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+		} else if !isDir && !entry.Type().IsRegular() {
+			continue
+		}
+
+		// Git's own files are never edited.
+		if entry.Name() == ".git" {
+			continue
+		}
+		if isDir && !w.opts.Hidden && strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if matchAny(w.opts.Exclude, entryRel) || ignored(ignores, entryRel, isDir) {
+			continue
+		}
+		if isDir {
+			if err := w.walk(full, entryRel, ignores); err != nil {
+				return err
+			}
+			continue
+		}
+		if len(w.opts.Include) > 0 && !matchAny(w.opts.Include, entryRel) {
+			continue
+		}
+		w.files = append(w.files, full)
+	}
+	return nil
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if Match(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
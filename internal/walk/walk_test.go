@@ -0,0 +1,163 @@
+package walk
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// makeTree creates files (with parent directories) under a temp dir.
+func makeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// relFiles runs Files and returns the results relative to root.
+func relFiles(t *testing.T, root string, opts Options) []string {
+	t.Helper()
+	files, err := Files(root, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var rel []string
+	for _, f := range files {
+		r, err := filepath.Rel(root, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rel = append(rel, filepath.ToSlash(r))
+	}
+	return rel
+}
+
+func TestFiles(t *testing.T) {
+	root := makeTree(t, map[string]string{
+		"main.go":          "",
+		"README.md":        "",
+		"cmd/ged/main.go":  "",
+		"vendor/x/x.go":    "",
+		".git/config":      "",
+		".github/ci.yml":   "",
+		"build/out.go":     "",
+		"logs/a.log":       "",
+		"logs/keep.log":    "",
+		".gitignore":       "build/\n*.log\n!keep.log\n",
+		"cmd/.gitignore":   "/ged/main.go\n",
+		"cmd/ged/other.go": "",
+	})
+
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{"defaults", Options{}, []string{
+			".gitignore", "README.md", "cmd/.gitignore", "cmd/ged/other.go", "logs/keep.log", "main.go", "vendor/x/x.go",
+		}},
+		{"include", Options{Include: []string{"*.go"}}, []string{
+			"cmd/ged/other.go", "main.go", "vendor/x/x.go",
+		}},
+		{"exclude", Options{Include: []string{"*.go"}, Exclude: []string{"vendor/**"}}, []string{
+			"cmd/ged/other.go", "main.go",
+		}},
+		{"no ignore", Options{Include: []string{"*.go"}, NoIgnore: true}, []string{
+			"build/out.go", "cmd/ged/main.go", "cmd/ged/other.go", "main.go", "vendor/x/x.go",
+		}},
+		{"hidden", Options{Include: []string{"*.yml"}, Hidden: true}, []string{
+			".github/ci.yml",
+		}},
+		{"hidden never enters .git", Options{Include: []string{"config"}, Hidden: true}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := relFiles(t, root, tt.opts)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFiles_InvalidGlob(t *testing.T) {
+	if _, err := Files(t.TempDir(), Options{Exclude: []string{"[a"}}); err == nil {
+		t.Error("expected error for invalid glob")
+	}
+}
+
+func TestFiles_SkipsSymlinkedDirs(t *testing.T) {
+	root := makeTree(t, map[string]string{"dir/a.txt": ""})
+	if err := os.Symlink(filepath.Join(root, "dir"), filepath.Join(root, "loop")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	if err := os.Symlink(filepath.Join(root, "dir", "a.txt"), filepath.Join(root, "b.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	got := relFiles(t, root, Options{})
+	if !reflect.DeepEqual(got, []string{"b.txt", "dir/a.txt"}) {
+		t.Errorf("got %q, want [b.txt dir/a.txt]", got)
+	}
+}
+
+func TestFiles_GitDirByName(t *testing.T) {
+	root := makeTree(t, map[string]string{".git/config": "", ".git/HEAD": ""})
+
+	got := relFiles(t, filepath.Join(root, ".git"), Options{})
+	if !reflect.DeepEqual(got, []string{"HEAD", "config"}) {
+		t.Errorf("got %q, want [HEAD config]", got)
+	}
+}
+
+func TestFiles_ParentIgnoreFiles(t *testing.T) {
+	root := makeTree(t, map[string]string{
+		".git/HEAD":         "",
+		".gitignore":        "*.gen.txt\n/src/skip/\n/top.txt\n",
+		"src/.gitignore":    "!keep.gen.txt\n",
+		"src/a.gen.txt":     "",
+		"src/keep.gen.txt":  "",
+		"src/b.txt":         "",
+		"src/top.txt":       "",
+		"src/skip/c.txt":    "",
+		"src/lib/d.gen.txt": "",
+		"src/lib/e.txt":     "",
+	})
+	src := filepath.Join(root, "src")
+
+	tests := []struct {
+		name string
+		dir  string
+		opts Options
+		want []string
+	}{
+		{"below the repository top", src, Options{}, []string{".gitignore", "b.txt", "keep.gen.txt", "lib/e.txt", "top.txt"}},
+		{"two levels down", filepath.Join(src, "lib"), Options{}, []string{"e.txt"}},
+		{"no ignore", filepath.Join(src, "lib"), Options{NoIgnore: true}, []string{"d.gen.txt", "e.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := relFiles(t, tt.dir, tt.opts)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Outside a repository, .gitignore files above the root don't apply.
+	if err := os.RemoveAll(filepath.Join(root, ".git")); err != nil {
+		t.Fatal(err)
+	}
+	if got := relFiles(t, filepath.Join(src, "lib"), Options{}); !reflect.DeepEqual(got, []string{"d.gen.txt", "e.txt"}) {
+		t.Errorf("outside a repository: got %q, want [d.gen.txt e.txt]", got)
+	}
+}